@@ -10,9 +10,21 @@ import (
 type Config struct {
 	ConsistencyAlgorithm string `yaml:"consistency_algorithm"`
 	Gossip               struct {
-		Port   int      `yaml:"port"`
-		Peers  []string `yaml:"peers"`
-		NodeID string   `yaml:"node_id"`
+		Port    int      `yaml:"port"`
+		Peers   []string `yaml:"peers"`
+		NodeID  string   `yaml:"node_id"`
+		Address string   `yaml:"address"` // 其他节点访问本节点 Gossip 服务的地址，默认 localhost:port
+
+		Interval     int    `yaml:"interval"`      // 向其他节点发送变更的间隔毫秒数，默认 1000
+		TombstoneTTL int    `yaml:"tombstone_ttl"` // 删除标记保留的秒数，应大于变更传播到所有节点所需的时间，默认 3600
+		SyncInterval int    `yaml:"sync_interval"` // 与随机节点进行反熵同步的间隔秒数，0 表示不同步，默认 30
+		Resolution   string `yaml:"resolution"`    // 并发写入的解决方式：lww 保留时间戳较大的写入，siblings 保留所有并发写入交给客户端解决，默认 lww
+
+		ProbeInterval    int `yaml:"probe_interval"`    // SWIM 探测周期的毫秒数，默认 1000
+		ProbeTimeout     int `yaml:"probe_timeout"`     // 等待探测确认的毫秒数，应小于探测周期，默认 500
+		SuspicionTimeout int `yaml:"suspicion_timeout"` // 被怀疑的成员在该毫秒数内没有反驳时判定为死亡，默认 5000
+		IndirectChecks   int `yaml:"indirect_checks"`   // 直接探测失败时请求间接探测的成员数量，默认 3
+		ReapTimeout      int `yaml:"reap_timeout"`      // 死亡或离开的成员在该秒数后从成员列表中移除，0 表示不移除，默认 3600
 	} `yaml:"gossip"`
 	Database struct {
 		Type     string `yaml:"type"`
@@ -22,6 +34,27 @@ type Config struct {
 		Password string `yaml:"password"`
 		DBName   string `yaml:"db_name"`
 	} `yaml:"database"`
+	Eviction struct {
+		Policy    string `yaml:"policy"`     // 淘汰策略：lru、lfu、lfu-aging、w-tinylfu、arc、random、noeviction
+		MaxMemory int64  `yaml:"max_memory"` // 内存上限，单位为字节，0 表示不限制
+		MaxKeys   int    `yaml:"max_keys"`   // 最大存储条数，0 表示不限制
+
+		LFULogFactor int `yaml:"lfu_log_factor"` // LFU 对数计数器的增长因子，越大计数增长越慢，默认 10
+		LFUDecayTime int `yaml:"lfu_decay_time"` // LFU 计数器每衰减一次所需的空闲分钟数，0 表示不衰减，默认 1
+	} `yaml:"eviction"`
+	AOF struct {
+		Enabled  bool   `yaml:"enabled"`  // 是否开启追加写日志
+		Filename string `yaml:"filename"` // 日志文件路径，默认 data/appendonly.aof
+		Fsync    string `yaml:"fsync"`    // 刷盘策略：always、everysec、no，默认 everysec
+
+		RewritePercentage int   `yaml:"rewrite_percentage"` // 文件比上次重写后增长超过该百分比时自动重写，0 表示不自动重写，默认 100
+		RewriteMinSize    int64 `yaml:"rewrite_min_size"`   // 文件小于该字节数时不自动重写，默认 64MB
+	} `yaml:"aof"`
+	Snapshot struct {
+		Dir      string `yaml:"dir"`      // 快照目录，默认 data/snapshots
+		Interval int    `yaml:"interval"` // 定时保存快照的间隔秒数，0 表示只通过管理接口手动保存
+		Retain   int    `yaml:"retain"`   // 保留最近的快照数量，默认 3
+	} `yaml:"snapshot"`
 }
 
 // LoadConfig 从配置文件中加载配置
@@ -36,6 +69,25 @@ func LoadConfig(filePath string) (*Config, error) {
 	// 初始化配置结构体
 	config := &Config{}
 
+	// 未在配置文件中出现的字段保留这里的默认值
+	config.Gossip.Interval = 1000
+	config.Gossip.TombstoneTTL = 3600
+	config.Gossip.SyncInterval = 30
+	config.Gossip.Resolution = "lww"
+	config.Gossip.ProbeInterval = 1000
+	config.Gossip.ProbeTimeout = 500
+	config.Gossip.SuspicionTimeout = 5000
+	config.Gossip.IndirectChecks = 3
+	config.Gossip.ReapTimeout = 3600
+	config.Eviction.LFULogFactor = 10
+	config.Eviction.LFUDecayTime = 1
+	config.AOF.Filename = "data/appendonly.aof"
+	config.AOF.Fsync = "everysec"
+	config.AOF.RewritePercentage = 100
+	config.AOF.RewriteMinSize = 64 << 20
+	config.Snapshot.Dir = "data/snapshots"
+	config.Snapshot.Retain = 3
+
 	// 解码 YAML 配置文件
 	decoder := yaml.NewDecoder(file)
 	if err := decoder.Decode(config); err != nil {
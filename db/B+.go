@@ -1,122 +1,338 @@
 package db
 
 import (
+	"cmp"
 	"fmt"
-	"strings"
-
+	"slices"
 	"sort"
+	"strings"
 )
 
 // 定义 B+树节点结构
-type BPlusNode struct {
+type BPlusNode[K any, V any] struct {
 	isLeaf   bool
-	keys     []string
-	children []*BPlusNode
-	next     *BPlusNode // 叶子节点通过next指针连接
+	keys     []K
+	values   []V // 仅叶子节点使用，与 keys 一一对应
+	children []*BPlusNode[K, V]
+	counts   []int            // 仅内部节点使用，counts[i] 是 children[i] 子树中键值对的数量
+	next     *BPlusNode[K, V] // 叶子节点通过next指针连接
+	prev     *BPlusNode[K, V] // 叶子节点通过prev指针反向连接
+}
+
+// BPlusTree 是有序的键值映射，值只存放在叶子节点中
+// 内部节点的第 i 个键是第 i+1 个子树的下界：children[i] 中的键都满足 keys[i-1] <= key < keys[i]
+// 内部节点同时记录每个子树的大小，因此可以在 O(log n) 时间内按排名定位键
+type BPlusTree[K any, V any] struct {
+	root    *BPlusNode[K, V]
+	degree  int // 每个节点最多容纳的键数量
+	size    int // 树中键值对的数量
+	compare func(a, b K) int
 }
 
-// BPlusTree 结构
-type BPlusTree struct {
-	root   *BPlusNode
-	degree int
+// 创建新的B+树，键按自然顺序排列
+func NewBPlusTree[K cmp.Ordered, V any](degree int) *BPlusTree[K, V] {
+	return NewBPlusTreeFunc[K, V](degree, cmp.Compare[K])
 }
 
-// 创建新的B+树
-func NewBPlusTree(degree int) *BPlusTree {
-	return &BPlusTree{
-		root:   &BPlusNode{isLeaf: true},
-		degree: degree,
+// NewBPlusTreeFunc 创建使用自定义比较函数的B+树
+// compare(a, b) 在 a < b 时返回负数，相等时返回 0，a > b 时返回正数
+func NewBPlusTreeFunc[K any, V any](degree int, compare func(a, b K) int) *BPlusTree[K, V] {
+	// 度小于3时节点无法在分裂和合并后保持半满
+	if degree < 3 {
+		degree = 3
 	}
+	return &BPlusTree[K, V]{
+		root:    &BPlusNode[K, V]{isLeaf: true},
+		degree:  degree,
+		compare: compare,
+	}
+}
+
+// Len 返回树中键值对的数量
+func (tree *BPlusTree[K, V]) Len() int {
+	return tree.size
 }
 
-// 插入一个键值对到B+树
-func (tree *BPlusTree) Insert(key string) {
-	root := tree.root
-	if len(root.keys) == tree.degree {
-		// 如果根节点已满，进行分裂
-		newRoot := &BPlusNode{isLeaf: false}
-		newRoot.children = append(newRoot.children, root)
-		tree.split(newRoot, 0)
-		tree.root = newRoot
+// count 返回以 node 为根的子树中键值对的数量
+func (node *BPlusNode[K, V]) count() int {
+	if node.isLeaf {
+		return len(node.keys)
 	}
-	tree.insertNonFull(tree.root, key)
+	total := 0
+	for _, count := range node.counts {
+		total += count
+	}
+	return total
 }
 
-// 分裂节点
-func (tree *BPlusTree) split(node *BPlusNode, index int) {
-	child := node.children[index]
-	midIndex := len(child.keys) / 2
-	midKey := child.keys[midIndex]
+// minKeys 返回非根节点至少需要容纳的键数量
+func (tree *BPlusTree[K, V]) minKeys() int {
+	return tree.degree / 2
+}
 
-	// 创建新节点并分裂
-	newNode := &BPlusNode{isLeaf: child.isLeaf}
-	newNode.keys = child.keys[midIndex+1:]
-	child.keys = child.keys[:midIndex]
+// search 在叶子节点中查找第一个不小于 key 的位置，并返回该位置的键是否等于 key
+func (tree *BPlusTree[K, V]) search(keys []K, key K) (int, bool) {
+	i := sort.Search(len(keys), func(i int) bool {
+		return tree.compare(keys[i], key) >= 0
+	})
+	return i, i < len(keys) && tree.compare(keys[i], key) == 0
+}
 
-	if child.isLeaf {
-		newNode.next = child.next
-		child.next = newNode
+// childIndex 返回内部节点中应当包含 key 的子节点下标
+func (tree *BPlusTree[K, V]) childIndex(node *BPlusNode[K, V], key K) int {
+	return sort.Search(len(node.keys), func(i int) bool {
+		return tree.compare(key, node.keys[i]) < 0
+	})
+}
+
+// Get 查询键对应的值
+func (tree *BPlusTree[K, V]) Get(key K) (V, bool) {
+	node := tree.root
+	for !node.isLeaf {
+		node = node.children[tree.childIndex(node, key)]
+	}
+
+	if i, found := tree.search(node.keys, key); found {
+		return node.values[i], true
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Put 插入或覆盖一个键值对，键已存在时返回被覆盖的旧值
+func (tree *BPlusTree[K, V]) Put(key K, value V) (V, bool) {
+	old, replaced, splitKey, sibling := tree.insert(tree.root, key, value)
+
+	// 根节点发生分裂，树高加一
+	if sibling != nil {
+		tree.root = &BPlusNode[K, V]{
+			keys:     []K{splitKey},
+			children: []*BPlusNode[K, V]{tree.root, sibling},
+			counts:   []int{tree.root.count(), sibling.count()},
+		}
+	}
+
+	if !replaced {
+		tree.size++
+	}
+	return old, replaced
+}
+
+// insert 递归插入键值对，节点溢出时分裂并返回上移的分隔键和新建的右兄弟节点
+func (tree *BPlusTree[K, V]) insert(node *BPlusNode[K, V], key K, value V) (old V, replaced bool, splitKey K, sibling *BPlusNode[K, V]) {
+	if node.isLeaf {
+		i, found := tree.search(node.keys, key)
+		if found {
+			old = node.values[i]
+			node.values[i] = value
+			return old, true, splitKey, nil
+		}
+		node.keys = slices.Insert(node.keys, i, key)
+		node.values = slices.Insert(node.values, i, value)
 	} else {
-		newNode.children = child.children[midIndex+1:]
-		child.children = child.children[:midIndex+1]
+		i := tree.childIndex(node, key)
+		var childKey K
+		var childSibling *BPlusNode[K, V]
+		old, replaced, childKey, childSibling = tree.insert(node.children[i], key, value)
+		if !replaced {
+			node.counts[i]++
+		}
+		if childSibling == nil {
+			return old, replaced, splitKey, nil
+		}
+		siblingCount := childSibling.count()
+		node.keys = slices.Insert(node.keys, i, childKey)
+		node.children = slices.Insert(node.children, i+1, childSibling)
+		node.counts[i] -= siblingCount
+		node.counts = slices.Insert(node.counts, i+1, siblingCount)
 	}
 
-	node.keys = append(node.keys[:index], append([]string{midKey}, node.keys[index+1:]...)...)
-	node.children = append(node.children[:index+1], append([]*BPlusNode{newNode}, node.children[index+1:]...)...)
+	if len(node.keys) > tree.degree {
+		splitKey, sibling = tree.split(node)
+	}
+	return old, replaced, splitKey, sibling
 }
 
-// 在非满节点插入
-func (tree *BPlusTree) insertNonFull(node *BPlusNode, key string) {
+// 分裂节点，返回上移到父节点的分隔键和新的右兄弟节点
+func (tree *BPlusTree[K, V]) split(node *BPlusNode[K, V]) (K, *BPlusNode[K, V]) {
+	midIndex := len(node.keys) / 2
+	newNode := &BPlusNode[K, V]{isLeaf: node.isLeaf}
+
 	if node.isLeaf {
-		node.keys = append(node.keys, key)
-		sort.Strings(node.keys)
-		return
+		// 叶子节点的分隔键复制到父节点，右半部分的键值都保留在叶子中
+		newNode.keys = slices.Clone(node.keys[midIndex:])
+		newNode.values = slices.Clone(node.values[midIndex:])
+		clear(node.values[midIndex:])
+		node.keys = node.keys[:midIndex]
+		node.values = node.values[:midIndex]
+
+		newNode.next = node.next
+		if newNode.next != nil {
+			newNode.next.prev = newNode
+		}
+		newNode.prev = node
+		node.next = newNode
+		return newNode.keys[0], newNode
 	}
 
-	i := 0
-	for i < len(node.keys) && key > node.keys[i] {
-		i++
+	// 内部节点的分隔键移动到父节点
+	midKey := node.keys[midIndex]
+	newNode.keys = slices.Clone(node.keys[midIndex+1:])
+	newNode.children = slices.Clone(node.children[midIndex+1:])
+	newNode.counts = slices.Clone(node.counts[midIndex+1:])
+	clear(node.children[midIndex+1:])
+	node.keys = node.keys[:midIndex]
+	node.children = node.children[:midIndex+1]
+	node.counts = node.counts[:midIndex+1]
+	return midKey, newNode
+}
+
+// Scan 按键的升序遍历所有键值对，visit 返回 false 时停止遍历
+func (tree *BPlusTree[K, V]) Scan(visit func(key K, value V) bool) {
+	node := tree.root
+	for !node.isLeaf {
+		node = node.children[0]
 	}
-	if len(node.children[i].keys) == tree.degree {
-		tree.split(node, i)
-		if key > node.keys[i] {
-			i++
+
+	for ; node != nil; node = node.next {
+		for i, key := range node.keys {
+			if !visit(key, node.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Rank 返回小于 key 的键的数量，即 key 在升序中的下标（从 0 开始），并返回 key 是否存在
+func (tree *BPlusTree[K, V]) Rank(key K) (int, bool) {
+	rank := 0
+	node := tree.root
+	for !node.isLeaf {
+		i := tree.childIndex(node, key)
+		for _, count := range node.counts[:i] {
+			rank += count
 		}
+		node = node.children[i]
 	}
-	tree.insertNonFull(node.children[i], key)
+
+	i, found := tree.search(node.keys, key)
+	return rank + i, found
 }
 
-// 查询B+树
-func (tree *BPlusTree) Search(key string) bool {
+// Iterator 是沿叶子链表移动的游标，可以向前或向后遍历
+// 游标在树被修改后失效，需要重新 Seek
+type Iterator[K any, V any] struct {
+	node  *BPlusNode[K, V]
+	index int
+}
+
+// Seek 返回定位到第一个不小于 key 的键上的游标
+// 所有键都小于 key 时游标无效，但仍可以通过 Prev 回到最后一个键
+func (tree *BPlusTree[K, V]) Seek(key K) *Iterator[K, V] {
 	node := tree.root
-	for node != nil {
+	for !node.isLeaf {
+		node = node.children[tree.childIndex(node, key)]
+	}
+
+	index, _ := tree.search(node.keys, key)
+	it := &Iterator[K, V]{node: node, index: index}
+	// 目标位置在当前叶子末尾时，移动到下一个叶子的开头
+	if index == len(node.keys) && node.next != nil {
+		it.node = node.next
+		it.index = 0
+	}
+	return it
+}
+
+// SeekRank 返回定位到升序中第 rank 个键（从 0 开始）上的游标，rank 超出范围时游标无效
+func (tree *BPlusTree[K, V]) SeekRank(rank int) *Iterator[K, V] {
+	if rank < 0 || rank >= tree.size {
+		return &Iterator[K, V]{}
+	}
+
+	node := tree.root
+	for !node.isLeaf {
 		i := 0
-		for i < len(node.keys) && key > node.keys[i] {
+		for rank >= node.counts[i] {
+			rank -= node.counts[i]
 			i++
 		}
+		node = node.children[i]
+	}
+	return &Iterator[K, V]{node: node, index: rank}
+}
 
-		if i < len(node.keys) && key == node.keys[i] {
-			return true
-		}
+// SeekFirst 返回定位到最小键上的游标
+func (tree *BPlusTree[K, V]) SeekFirst() *Iterator[K, V] {
+	node := tree.root
+	for !node.isLeaf {
+		node = node.children[0]
+	}
+	return &Iterator[K, V]{node: node, index: 0}
+}
 
-		if node.isLeaf {
-			return false
-		}
+// SeekLast 返回定位到最大键上的游标
+func (tree *BPlusTree[K, V]) SeekLast() *Iterator[K, V] {
+	node := tree.root
+	for !node.isLeaf {
+		node = node.children[len(node.children)-1]
+	}
+	it := &Iterator[K, V]{node: node, index: len(node.keys)}
+	it.Prev()
+	return it
+}
 
-		node = node.children[i]
+// Valid 判断游标是否指向一个有效的键值对
+func (it *Iterator[K, V]) Valid() bool {
+	return it.node != nil && it.index >= 0 && it.index < len(it.node.keys)
+}
+
+// Key 返回游标当前指向的键
+func (it *Iterator[K, V]) Key() K {
+	return it.node.keys[it.index]
+}
+
+// Value 返回游标当前指向的值
+func (it *Iterator[K, V]) Value() V {
+	return it.node.values[it.index]
+}
+
+// Next 将游标移动到下一个键，越过最大键后游标无效
+func (it *Iterator[K, V]) Next() {
+	if it.node == nil {
+		return
+	}
+	it.index++
+	if it.index >= len(it.node.keys) && it.node.next != nil {
+		it.node = it.node.next
+		it.index = 0
+	}
+}
+
+// Prev 将游标移动到上一个键，越过最小键后游标无效
+func (it *Iterator[K, V]) Prev() {
+	if it.node == nil {
+		return
+	}
+	it.index--
+	if it.index < 0 {
+		it.node = it.node.prev
+		if it.node != nil {
+			it.index = len(it.node.keys) - 1
+		}
 	}
-	return false
 }
 
 // 打印树的结构
-func (tree *BPlusTree) PrintTree(node *BPlusNode, level int) {
+func (tree *BPlusTree[K, V]) PrintTree(node *BPlusNode[K, V], level int) {
 	if node == nil {
 		return
 	}
 	fmt.Printf("%sLevel %d: ", strings.Repeat(" ", level*2), level)
 
 	for _, key := range node.keys {
-		fmt.Printf("%s ", key)
+		fmt.Printf("%v ", key)
 	}
 	fmt.Println()
 	for _, child := range node.children {
@@ -124,81 +340,56 @@ func (tree *BPlusTree) PrintTree(node *BPlusNode, level int) {
 	}
 }
 
-// 删除节点
-func (tree *BPlusTree) Delete(key string) {
-	if tree.root == nil {
-		return
+// Delete 删除一个键，返回被删除的值
+func (tree *BPlusTree[K, V]) Delete(key K) (V, bool) {
+	old, deleted := tree.delete(tree.root, key)
+	if !deleted {
+		return old, false
 	}
-
-	// 调用递归删除函数
-	tree.delete(tree.root, key)
+	tree.size--
 
 	// 如果根节点没有键了，并且不是叶子节点，更新根节点
 	if len(tree.root.keys) == 0 && !tree.root.isLeaf {
 		tree.root = tree.root.children[0]
 	}
+	return old, true
 }
 
-// 删除节点中的一个键
-func (tree *BPlusTree) delete(node *BPlusNode, key string) {
+// 从以 node 为根的子树中删除一个键，子节点不足半满时进行修复
+// 内部节点中的分隔键即使对应的键已被删除也仍是合法的边界，因此无需替换
+func (tree *BPlusTree[K, V]) delete(node *BPlusNode[K, V], key K) (V, bool) {
 	// 叶子节点删除
 	if node.isLeaf {
-		index := findKeyIndex(node.keys, key)
-		if index != -1 {
-			// 找到并删除该键
-			node.keys = append(node.keys[:index], node.keys[index+1:]...)
+		index, found := tree.search(node.keys, key)
+		if !found {
+			var zero V
+			return zero, false
 		}
-		return
+		old := node.values[index]
+		node.keys = slices.Delete(node.keys, index, index+1)
+		node.values = slices.Delete(node.values, index, index+1)
+		return old, true
 	}
 
-	// 内部节点删除
-	// 找到该键在内部节点的索引
-	index := findKeyIndex(node.keys, key)
-
-	// 如果索引在范围内，且该键在当前节点
-	if index < len(node.keys) && key == node.keys[index] {
-		// 如果该节点的子节点是叶子节点，直接删除
-		if len(node.children[index].keys) > 0 {
-			node.keys[index] = tree.getPred(node, index)
-			tree.delete(node.children[index], node.keys[index]) // 删除该键
-		} else {
-			node.keys = append(node.keys[:index], node.keys[index+1:]...)
-		}
-		return
+	// 内部节点，递归到包含该键的子节点中删除
+	index := tree.childIndex(node, key)
+	old, deleted := tree.delete(node.children[index], key)
+	if !deleted {
+		return old, false
 	}
-
-	// 如果该键在子节点中
-	if len(node.children[index].keys) <= tree.degree/2 {
+	node.counts[index]--
+	if len(node.children[index].keys) < tree.minKeys() {
 		tree.fix(node, index)
 	}
-
-	tree.delete(node.children[index], key)
-}
-
-// 获取前驱元素（最大值）
-func (tree *BPlusTree) getPred(node *BPlusNode, index int) string {
-	current := node.children[index]
-	for !current.isLeaf {
-		current = current.children[len(current.children)-1]
-	}
-	return current.keys[len(current.keys)-1]
-}
-
-// 获取后继元素（最小值）
-func (tree *BPlusTree) getSucc(node *BPlusNode, index int) string {
-	current := node.children[index+1]
-	for !current.isLeaf {
-		current = current.children[0]
-	}
-	return current.keys[0]
+	return old, deleted
 }
 
 // 修复不平衡的节点
-func (tree *BPlusTree) fix(node *BPlusNode, index int) {
+func (tree *BPlusTree[K, V]) fix(node *BPlusNode[K, V], index int) {
 	// 如果兄弟节点有足够的键，可以借一个
-	if index > 0 && len(node.children[index-1].keys) > tree.degree/2 {
+	if index > 0 && len(node.children[index-1].keys) > tree.minKeys() {
 		tree.borrowFromPrev(node, index)
-	} else if index < len(node.children)-1 && len(node.children[index+1].keys) > tree.degree/2 {
+	} else if index < len(node.children)-1 && len(node.children[index+1].keys) > tree.minKeys() {
 		tree.borrowFromNext(node, index)
 	} else {
 		// 否则，合并兄弟节点
@@ -211,71 +402,200 @@ func (tree *BPlusTree) fix(node *BPlusNode, index int) {
 }
 
 // 从前一个兄弟节点借一个元素
-func (tree *BPlusTree) borrowFromPrev(node *BPlusNode, index int) {
+func (tree *BPlusTree[K, V]) borrowFromPrev(node *BPlusNode[K, V], index int) {
 	child := node.children[index]
 	sibling := node.children[index-1]
+	last := len(sibling.keys) - 1
 
-	// 将父节点的键移动到子节点
-	child.keys = append([]string{node.keys[index-1]}, child.keys...)
-	node.keys[index-1] = sibling.keys[len(sibling.keys)-1]
-
-	// 如果兄弟节点不是叶子节点，父节点的子节点需要移动
-	if !child.isLeaf {
-		child.children = append([]*BPlusNode{sibling.children[len(sibling.children)-1]}, child.children...)
-		sibling.children = sibling.children[:len(sibling.children)-1]
+	if child.isLeaf {
+		// 叶子节点直接移动键值对，并把父节点的分隔键更新为子节点新的最小键
+		child.keys = slices.Insert(child.keys, 0, sibling.keys[last])
+		child.values = slices.Insert(child.values, 0, sibling.values[last])
+		sibling.keys = slices.Delete(sibling.keys, last, last+1)
+		sibling.values = slices.Delete(sibling.values, last, last+1)
+		node.keys[index-1] = child.keys[0]
+		node.counts[index-1]--
+		node.counts[index]++
+		return
 	}
 
-	// 删除兄弟节点的键
-	sibling.keys = sibling.keys[:len(sibling.keys)-1]
+	// 内部节点通过父节点旋转：父节点的分隔键下移，兄弟节点的最大键上移
+	moved := sibling.counts[last+1]
+	child.keys = slices.Insert(child.keys, 0, node.keys[index-1])
+	child.children = slices.Insert(child.children, 0, sibling.children[last+1])
+	child.counts = slices.Insert(child.counts, 0, moved)
+	node.keys[index-1] = sibling.keys[last]
+	sibling.keys = slices.Delete(sibling.keys, last, last+1)
+	sibling.children = slices.Delete(sibling.children, last+1, last+2)
+	sibling.counts = slices.Delete(sibling.counts, last+1, last+2)
+	node.counts[index-1] -= moved
+	node.counts[index] += moved
 }
 
 // 从后一个兄弟节点借一个元素
-func (tree *BPlusTree) borrowFromNext(node *BPlusNode, index int) {
+func (tree *BPlusTree[K, V]) borrowFromNext(node *BPlusNode[K, V], index int) {
 	child := node.children[index]
 	sibling := node.children[index+1]
 
-	// 将父节点的键移动到子节点
-	child.keys = append(child.keys, node.keys[index])
-	node.keys[index] = sibling.keys[0]
-
-	// 如果兄弟节点不是叶子节点，父节点的子节点需要移动
-	if !child.isLeaf {
-		child.children = append(child.children, sibling.children[0])
-		sibling.children = sibling.children[1:]
+	if child.isLeaf {
+		// 叶子节点直接移动键值对，并把父节点的分隔键更新为兄弟节点新的最小键
+		child.keys = append(child.keys, sibling.keys[0])
+		child.values = append(child.values, sibling.values[0])
+		sibling.keys = slices.Delete(sibling.keys, 0, 1)
+		sibling.values = slices.Delete(sibling.values, 0, 1)
+		node.keys[index] = sibling.keys[0]
+		node.counts[index]++
+		node.counts[index+1]--
+		return
 	}
 
-	// 删除兄弟节点的键
-	sibling.keys = sibling.keys[1:]
+	// 内部节点通过父节点旋转：父节点的分隔键下移，兄弟节点的最小键上移
+	moved := sibling.counts[0]
+	child.keys = append(child.keys, node.keys[index])
+	child.children = append(child.children, sibling.children[0])
+	child.counts = append(child.counts, moved)
+	node.keys[index] = sibling.keys[0]
+	sibling.keys = slices.Delete(sibling.keys, 0, 1)
+	sibling.children = slices.Delete(sibling.children, 0, 1)
+	sibling.counts = slices.Delete(sibling.counts, 0, 1)
+	node.counts[index] += moved
+	node.counts[index+1] -= moved
 }
 
 // 合并节点
-func (tree *BPlusTree) merge(node *BPlusNode, index int) {
+func (tree *BPlusTree[K, V]) merge(node *BPlusNode[K, V], index int) {
 	left := node.children[index]
 	right := node.children[index+1]
 
-	// 将父节点的键移到左子节点
-	left.keys = append(left.keys, node.keys[index])
-	left.keys = append(left.keys, right.keys...)
-
-	// 如果右子节点不是叶子节点，合并子节点
-	if !left.isLeaf {
+	if left.isLeaf {
+		// 叶子节点直接拼接，并跳过被合并的右节点重新连接叶子链表
+		left.keys = append(left.keys, right.keys...)
+		left.values = append(left.values, right.values...)
+		left.next = right.next
+		if left.next != nil {
+			left.next.prev = left
+		}
+	} else {
+		// 内部节点需要把父节点的分隔键一起下移
+		left.keys = append(left.keys, node.keys[index])
+		left.keys = append(left.keys, right.keys...)
 		left.children = append(left.children, right.children...)
+		left.counts = append(left.counts, right.counts...)
 	}
 
 	// 删除父节点的键和右子节点
-	node.keys = append(node.keys[:index], node.keys[index+1:]...)
-	node.children = append(node.children[:index+1], node.children[index+2:]...)
+	node.counts[index] += node.counts[index+1]
+	node.keys = slices.Delete(node.keys, index, index+1)
+	node.children = slices.Delete(node.children, index+1, index+2)
+	node.counts = slices.Delete(node.counts, index+1, index+2)
 }
 
-// 查找键在节点中的位置
-func findKeyIndex(keys []string, key string) int {
-	for i, k := range keys {
-		if key == k {
-			return i
+// Validate 检查B+树的结构不变量，返回发现的第一个问题
+// 检查项包括：节点内键严格递增、非根节点半满、所有叶子深度一致、
+// 分隔键正确划分子树、子树大小的记录正确、叶子链表正反向完整且与树中叶子的顺序一致、键值对数量正确
+func (tree *BPlusTree[K, V]) Validate() error {
+	if tree.root == nil {
+		return fmt.Errorf("root is nil")
+	}
+	if !tree.root.isLeaf && len(tree.root.keys) == 0 {
+		return fmt.Errorf("internal root has no keys")
+	}
+
+	var leaves []*BPlusNode[K, V]
+	leafDepth := -1
+	count := 0
+
+	// 递归检查子树，lower/upper 为父节点给出的键范围 [lower, upper)
+	var check func(node *BPlusNode[K, V], depth int, lower, upper *K) error
+	check = func(node *BPlusNode[K, V], depth int, lower, upper *K) error {
+		if len(node.keys) > tree.degree {
+			return fmt.Errorf("node at depth %d has %d keys, more than degree %d", depth, len(node.keys), tree.degree)
 		}
-		if key < k {
-			break
+		if node != tree.root && len(node.keys) < tree.minKeys() {
+			return fmt.Errorf("node at depth %d has %d keys, fewer than minimum %d", depth, len(node.keys), tree.minKeys())
 		}
+
+		for i, key := range node.keys {
+			if i > 0 && tree.compare(node.keys[i-1], key) >= 0 {
+				return fmt.Errorf("keys not strictly increasing at depth %d: %v >= %v", depth, node.keys[i-1], key)
+			}
+			if lower != nil && tree.compare(key, *lower) < 0 {
+				return fmt.Errorf("key %v at depth %d is below separator %v", key, depth, *lower)
+			}
+			if upper != nil && tree.compare(key, *upper) >= 0 {
+				return fmt.Errorf("key %v at depth %d is not below separator %v", key, depth, *upper)
+			}
+		}
+
+		if node.isLeaf {
+			if len(node.children) != 0 {
+				return fmt.Errorf("leaf at depth %d has children", depth)
+			}
+			if len(node.values) != len(node.keys) {
+				return fmt.Errorf("leaf at depth %d has %d keys but %d values", depth, len(node.keys), len(node.values))
+			}
+			if leafDepth == -1 {
+				leafDepth = depth
+			} else if leafDepth != depth {
+				return fmt.Errorf("leaves at different depths %d and %d", leafDepth, depth)
+			}
+			leaves = append(leaves, node)
+			count += len(node.keys)
+			return nil
+		}
+
+		if len(node.values) != 0 {
+			return fmt.Errorf("internal node at depth %d stores values", depth)
+		}
+		if len(node.children) != len(node.keys)+1 {
+			return fmt.Errorf("internal node at depth %d has %d keys but %d children", depth, len(node.keys), len(node.children))
+		}
+		if len(node.counts) != len(node.children) {
+			return fmt.Errorf("internal node at depth %d has %d children but %d counts", depth, len(node.children), len(node.counts))
+		}
+		for i, child := range node.children {
+			childLower, childUpper := lower, upper
+			if i > 0 {
+				childLower = &node.keys[i-1]
+			}
+			if i < len(node.keys) {
+				childUpper = &node.keys[i]
+			}
+			before := count
+			if err := check(child, depth+1, childLower, childUpper); err != nil {
+				return err
+			}
+			if count-before != node.counts[i] {
+				return fmt.Errorf("child %d at depth %d holds %d keys but is counted as %d", i, depth+1, count-before, node.counts[i])
+			}
+		}
+		return nil
+	}
+
+	if err := check(tree.root, 0, nil, nil); err != nil {
+		return err
 	}
-	return -1
+
+	if count != tree.size {
+		return fmt.Errorf("tree holds %d keys but size is %d", count, tree.size)
+	}
+
+	// 叶子链表必须按顺序串起树中的所有叶子
+	for i, leaf := range leaves {
+		var wantPrev, wantNext *BPlusNode[K, V]
+		if i > 0 {
+			wantPrev = leaves[i-1]
+		}
+		if i < len(leaves)-1 {
+			wantNext = leaves[i+1]
+		}
+		if leaf.prev != wantPrev {
+			return fmt.Errorf("leaf %d has a broken prev link", i)
+		}
+		if leaf.next != wantNext {
+			return fmt.Errorf("leaf %d has a broken next link", i)
+		}
+	}
+
+	return nil
 }
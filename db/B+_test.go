@@ -0,0 +1,204 @@
+package db
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testDegrees 是随机化测试覆盖的B+树的度，包括允许的最小值以及实际使用的 indexDegree
+var testDegrees = []int{3, 4, 5, 8, 16, indexDegree}
+
+// TestBPlusTreeDifferential 在每个度的树和作为参照的 map 上执行相同的随机插入、删除和查询并比较结果，
+// 定期检查结构不变量以及遍历和排名查询。较小的键取值范围带来更多的覆盖写和删除命中，从而频繁触发借用与合并
+func TestBPlusTreeDifferential(t *testing.T) {
+	ops := 300000
+	if testing.Short() {
+		ops = 10000
+	}
+
+	for _, degree := range testDegrees {
+		for _, keySpace := range []int{16, 1000, 100000} {
+			t.Run(fmt.Sprintf("degree=%d/keys=%d", degree, keySpace), func(t *testing.T) {
+				t.Parallel()
+				seed := uint64(time.Now().UnixNano())
+				rng := rand.New(rand.NewPCG(seed, uint64(degree)))
+				if err := checkBPlusTree(rng, degree, ops, keySpace); err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+			})
+		}
+	}
+}
+
+// TestBPlusTreeSequential 按升序插入后分别按升序和降序删除，并定期检查结构不变量
+// 顺序写入总是落在最右侧的叶子上，是分裂和合并最集中的情况
+func TestBPlusTreeSequential(t *testing.T) {
+	n := 100000
+	if testing.Short() {
+		n = 2000
+	}
+	validateEvery := max(1, n/100)
+
+	for _, degree := range testDegrees {
+		for _, descending := range []bool{false, true} {
+			t.Run(fmt.Sprintf("degree=%d/descending=%v", degree, descending), func(t *testing.T) {
+				t.Parallel()
+				tree := NewBPlusTree[int, int](degree)
+				for i := 0; i < n; i++ {
+					tree.Put(i, i)
+					if i%validateEvery != 0 {
+						continue
+					}
+					if err := tree.Validate(); err != nil {
+						t.Fatalf("after inserting %d: %v", i, err)
+					}
+				}
+				for i := 0; i < n; i++ {
+					key := i
+					if descending {
+						key = n - 1 - i
+					}
+					if value, deleted := tree.Delete(key); !deleted || value != key {
+						t.Fatalf("Delete(%d) returned (%d, %v)", key, value, deleted)
+					}
+					if i%validateEvery != 0 {
+						continue
+					}
+					if err := tree.Validate(); err != nil {
+						t.Fatalf("after deleting %d: %v", key, err)
+					}
+				}
+				if tree.Len() != 0 || tree.SeekFirst().Valid() {
+					t.Fatalf("tree not empty after deleting every key")
+				}
+			})
+		}
+	}
+}
+
+// checkBPlusTree 执行 ops 次随机操作并与参照 map 比较
+func checkBPlusTree(rng *rand.Rand, degree, ops, keySpace int) error {
+	tree := NewBPlusTree[int, int](degree)
+	reference := make(map[int]int)
+	validateEvery := max(1, ops/200)
+
+	for op := 0; op < ops; op++ {
+		key := rng.IntN(keySpace)
+
+		switch n := rng.IntN(10); {
+		case n < 5:
+			old, replaced := tree.Put(key, op)
+			wantOld, wantReplaced := reference[key]
+			if replaced != wantReplaced || old != wantOld {
+				return fmt.Errorf("op %d: Put(%d) returned (%d, %v), want (%d, %v)", op, key, old, replaced, wantOld, wantReplaced)
+			}
+			reference[key] = op
+		case n < 9:
+			old, deleted := tree.Delete(key)
+			wantOld, wantDeleted := reference[key]
+			if deleted != wantDeleted || old != wantOld {
+				return fmt.Errorf("op %d: Delete(%d) returned (%d, %v), want (%d, %v)", op, key, old, deleted, wantOld, wantDeleted)
+			}
+			delete(reference, key)
+		default:
+			value, found := tree.Get(key)
+			wantValue, wantFound := reference[key]
+			if found != wantFound || value != wantValue {
+				return fmt.Errorf("op %d: Get(%d) returned (%d, %v), want (%d, %v)", op, key, value, found, wantValue, wantFound)
+			}
+		}
+
+		if tree.Len() != len(reference) {
+			return fmt.Errorf("op %d: Len() = %d, want %d", op, tree.Len(), len(reference))
+		}
+
+		if op%validateEvery == 0 || op == ops-1 {
+			if err := tree.Validate(); err != nil {
+				return fmt.Errorf("op %d: %w", op, err)
+			}
+			if err := checkIteration(rng, tree, reference, keySpace); err != nil {
+				return fmt.Errorf("op %d: %w", op, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkIteration 比较从随机位置开始的正反向遍历结果以及排名查询与参照集合是否一致
+func checkIteration(rng *rand.Rand, tree *BPlusTree[int, int], reference map[int]int, keySpace int) error {
+	keys := make([]int, 0, len(reference))
+	for key := range reference {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	// 全量正向遍历
+	i := 0
+	for it := tree.SeekFirst(); it.Valid(); it.Next() {
+		if i >= len(keys) || it.Key() != keys[i] || it.Value() != reference[keys[i]] {
+			return fmt.Errorf("forward iteration diverged at position %d", i)
+		}
+		i++
+	}
+	if i != len(keys) {
+		return fmt.Errorf("forward iteration returned %d keys, want %d", i, len(keys))
+	}
+
+	// 全量反向遍历
+	i = len(keys) - 1
+	for it := tree.SeekLast(); it.Valid(); it.Prev() {
+		if i < 0 || it.Key() != keys[i] {
+			return fmt.Errorf("reverse iteration diverged at position %d", i)
+		}
+		i--
+	}
+	if i != -1 {
+		return fmt.Errorf("reverse iteration stopped with %d keys remaining", i+1)
+	}
+
+	// 从随机位置 Seek 后分别向两个方向遍历
+	target := rng.IntN(keySpace)
+	start, _ := slices.BinarySearch(keys, target)
+	it := tree.Seek(target)
+	for i = start; i < len(keys); i++ {
+		if !it.Valid() || it.Key() != keys[i] {
+			return fmt.Errorf("Seek(%d) forward diverged at position %d", target, i)
+		}
+		it.Next()
+	}
+	if it.Valid() {
+		return fmt.Errorf("Seek(%d) forward returned extra key %d", target, it.Key())
+	}
+
+	it = tree.Seek(target)
+	it.Prev()
+	for i = start - 1; i >= 0; i-- {
+		if !it.Valid() || it.Key() != keys[i] {
+			return fmt.Errorf("Seek(%d) backward diverged at position %d", target, i)
+		}
+		it.Prev()
+	}
+	if it.Valid() {
+		return fmt.Errorf("Seek(%d) backward returned extra key %d", target, it.Key())
+	}
+
+	// 排名查询与有序键集合中的下标一致
+	rank, found := tree.Rank(target)
+	wantFound := start < len(keys) && keys[start] == target
+	if rank != start || found != wantFound {
+		return fmt.Errorf("Rank(%d) returned (%d, %v), want (%d, %v)", target, rank, found, start, wantFound)
+	}
+	it = tree.SeekRank(start)
+	if start < len(keys) && (!it.Valid() || it.Key() != keys[start]) {
+		return fmt.Errorf("SeekRank(%d) did not return key %d", start, keys[start])
+	}
+	if start == len(keys) && it.Valid() {
+		return fmt.Errorf("SeekRank(%d) returned key %d past the end", start, it.Key())
+	}
+
+	return nil
+}
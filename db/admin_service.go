@@ -0,0 +1,76 @@
+package db
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// SaveSnapshot 在后台保存快照
+// @Summary 保存快照
+// @Description 在后台把当前数据保存为一个新的快照文件，保存进度和结果通过 GET /admin/snapshot 查询。
+// @Tags admin
+// @Produce  json
+// @Success 202 {object} KVResponse "已开始保存"
+// @Failure 409 {object} KVResponse "已有快照正在保存"
+// @Router /admin/snapshot [post]
+func (db *EchoDB) SaveSnapshot(context *gin.Context) {
+	if err := db.BackgroundSave(); err != nil {
+		respondError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusAccepted, KVResponse{
+		Code:    "202",
+		Message: "background saving started",
+	})
+}
+
+// GetSnapshotStatus 查询快照保存的状态
+// @Summary 查询快照状态
+// @Description 返回是否正在保存快照，以及最近一次保存的时间、文件、键数量和失败原因。
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} KVResponse "查询成功"
+// @Router /admin/snapshot [get]
+func (db *EchoDB) GetSnapshotStatus(context *gin.Context) {
+	context.JSON(http.StatusOK, KVResponse{
+		Code:    "200",
+		Message: "success",
+		Data:    db.SnapshotStatus(),
+	})
+}
+
+// RewriteAOFLog 在后台重写 AOF
+// @Summary 重写AOF
+// @Description 在后台根据当前数据生成最精简的追加写日志并原子地替换原文件，重写期间的新写入不会丢失。
+// @Tags admin
+// @Produce  json
+// @Success 202 {object} KVResponse "已开始重写"
+// @Failure 409 {object} KVResponse "未开启AOF或已有重写正在进行"
+// @Router /admin/aof/rewrite [post]
+func (db *EchoDB) RewriteAOFLog(context *gin.Context) {
+	if err := db.BackgroundRewriteAOF(); err != nil {
+		respondError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusAccepted, KVResponse{
+		Code:    "202",
+		Message: "background AOF rewrite started",
+	})
+}
+
+// GetAOFStatus 查询 AOF 的状态
+// @Summary 查询AOF状态
+// @Description 返回AOF当前的文件长度、上次重写后的基准长度、是否正在重写以及最近一次重写的结果。
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} KVResponse "查询成功"
+// @Router /admin/aof [get]
+func (db *EchoDB) GetAOFStatus(context *gin.Context) {
+	context.JSON(http.StatusOK, KVResponse{
+		Code:    "200",
+		Message: "success",
+		Data:    db.AOFStatus(),
+	})
+}
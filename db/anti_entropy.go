@@ -0,0 +1,359 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"hash/fnv"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	merkleFanout      = 16  // 摘要不一致的区间拆分出的子区间数量，即 Merkle 树的分支数
+	merkleLeafSize    = 128 // 区间内的键不超过该数量时不再拆分，直接交换区间内的数据
+	repairBatchRanges = 8   // 每次修复请求最多包含的区间数量
+)
+
+// KeyRange 是左闭右开的键区间 [Start, End)，End 为空表示没有上界
+type KeyRange struct {
+	Start string `json:"start"`
+	End   string `json:"end,omitempty"`
+}
+
+// contains 判断键是否落在区间内
+func (r KeyRange) contains(key string) bool {
+	return key >= r.Start && (r.End == "" || key < r.End)
+}
+
+// RangeDigest 是 Merkle 树中一个节点的摘要，由区间内每个键的键名、时间戳、版本向量和兄弟值的哈希相加得到
+// 被本节点淘汰的键按淘汰时的版本参与计算，两个节点上摘要相同的区间被认为数据一致，不需要继续比较
+type RangeDigest struct {
+	KeyRange
+	Hash  uint64 `json:"hash"`
+	Count int    `json:"count"` // 区间内未过期的键和被淘汰的键的数量
+}
+
+// DigestRequest 请求对方比较一组区间的摘要
+type DigestRequest struct {
+	NodeID  string        `json:"node_id"`
+	Digests []RangeDigest `json:"digests"`
+}
+
+// DigestResponse 返回摘要与本地不一致的区间在请求中的下标，以及这些区间在对方的键数量
+// 对方的键比请求方多时，请求方本地的键不足以拆分区间，因此对方同时返回按自己的键拆分的子区间
+type DigestResponse struct {
+	Mismatched []int        `json:"mismatched"`
+	Counts     []int        `json:"counts"` // 与 Mismatched 一一对应
+	Splits     [][]KeyRange `json:"splits"` // 与 Mismatched 一一对应，对方的键不比请求方多或不超过 merkleLeafSize 时为空
+}
+
+// RepairRequest 发送一组区间内的全部数据，并请求对方返回同一组区间内的数据
+type RepairRequest struct {
+	NodeID    string     `json:"node_id"`
+	Ranges    []KeyRange `json:"ranges"`
+	Mutations []Mutation `json:"mutations"`
+	Evicted   []Mutation `json:"evicted,omitempty"` // 区间内被请求方淘汰的键的版本，不含值，对方不再返回这些版本
+}
+
+// RepairResponse 返回区间内比请求中更新或请求中没有的数据
+type RepairResponse struct {
+	Mutations []Mutation `json:"mutations"`
+}
+
+// rangeDigests 计算每个区间在本地的摘要
+func (db *EchoDB) rangeDigests(ranges []KeyRange) []RangeDigest {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	now := time.Now()
+	digests := make([]RangeDigest, len(ranges))
+	for i, r := range ranges {
+		digests[i] = db.digestRange(r, now)
+	}
+	return digests
+}
+
+// digestRange 遍历区间计算摘要，已过期的键不参与计算，调用方必须持有读锁
+// 删除标记不参与计算：只有删除标记不同的区间，两边可见的数据仍然一致
+// 被淘汰的键按淘汰时的版本计入，使只因本地淘汰而不同的区间在两边摘要相同
+// 每个键的哈希在版本变化时已经算好，同步时只需遍历区间把它们相加
+func (db *EchoDB) digestRange(r KeyRange, now time.Time) RangeDigest {
+	digest := RangeDigest{KeyRange: r}
+	for it := db.data.Seek(r.Start); it.Valid() && r.contains(it.Key()); it.Next() {
+		item := it.Value()
+		if item.expired(now) {
+			continue
+		}
+		entry := item.digest
+		if entry == 0 {
+			entry = digestEntry(it.Key(), item.Stamp, item.Clock, siblingStamps(item.Siblings))
+		}
+		digest.Hash += entry
+		digest.Count++
+	}
+	if db.evicted != nil {
+		for it := db.evicted.Seek(r.Start); it.Valid() && r.contains(it.Key()); it.Next() {
+			evicted := it.Value()
+			digest.Hash += digestEntry(it.Key(), evicted.Stamp, evicted.Clock, evicted.Siblings)
+			digest.Count++
+		}
+	}
+	return digest
+}
+
+// digestEntry 计算一个键在摘要中的哈希，区间摘要是这些哈希的和，与遍历顺序无关
+func digestEntry(key string, stamp Stamp, clock VersionVector, siblings []Stamp) uint64 {
+	hash := fnv.New64a()
+	var buf [8]byte
+	hash.Write([]byte(key))
+	hash.Write([]byte{0})
+	writeStamp(hash, stamp)
+	for _, node := range slices.Sorted(maps.Keys(clock)) {
+		hash.Write([]byte(node))
+		hash.Write([]byte{0})
+		binary.BigEndian.PutUint64(buf[:], clock[node])
+		hash.Write(buf[:])
+	}
+	for _, sibling := range siblings {
+		writeStamp(hash, sibling)
+	}
+	return hash.Sum64()
+}
+
+// siblingStamps 返回兄弟值的时间戳
+func siblingStamps(siblings []Sibling) []Stamp {
+	if len(siblings) == 0 {
+		return nil
+	}
+	stamps := make([]Stamp, len(siblings))
+	for i, sibling := range siblings {
+		stamps[i] = sibling.Stamp
+	}
+	return stamps
+}
+
+// writeStamp 把时间戳写入摘要
+func writeStamp(w io.Writer, stamp Stamp) {
+	var buf [12]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(stamp.Time))
+	binary.BigEndian.PutUint32(buf[8:], stamp.Logical)
+	w.Write(buf[:])
+	w.Write([]byte(stamp.Node))
+	w.Write([]byte{0})
+}
+
+// splitRange 按本地键的排名把区间拆分为至多 parts 个键数量大致相等的相邻子区间
+// 排名由B+树记录的子树大小直接定位，不需要遍历区间
+func (db *EchoDB) splitRange(r KeyRange, parts int) []KeyRange {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	low, _ := db.data.Rank(r.Start)
+	high := db.data.Len()
+	if r.End != "" {
+		high, _ = db.data.Rank(r.End)
+	}
+
+	ranges := make([]KeyRange, 0, parts)
+	start := r.Start
+	for i := 1; i < parts; i++ {
+		it := db.data.SeekRank(low + (high-low)*i/parts)
+		if !it.Valid() || it.Key() <= start || !r.contains(it.Key()) {
+			continue
+		}
+		ranges = append(ranges, KeyRange{Start: start, End: it.Key()})
+		start = it.Key()
+	}
+	return append(ranges, KeyRange{Start: start, End: r.End})
+}
+
+// rangeMutations 导出区间内未过期的数据和删除标记，用于修复不一致的区间
+func (db *EchoDB) rangeMutations(ranges []KeyRange) []Mutation {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	now := time.Now()
+	var mutations []Mutation
+	for _, r := range ranges {
+		for it := db.data.Seek(r.Start); it.Valid() && r.contains(it.Key()); it.Next() {
+			item := it.Value()
+			if item.expired(now) {
+				continue
+			}
+			mutations = append(mutations, mutationOf(it.Key(), item))
+		}
+	}
+	for key, tombstone := range db.tombstones {
+		for _, r := range ranges {
+			if r.contains(key) {
+				mutations = append(mutations, Mutation{Key: key, Stamp: tombstone.Stamp, Clock: tombstone.Clock})
+				break
+			}
+		}
+	}
+	return mutations
+}
+
+// rangeEvicted 导出区间内被本节点淘汰的键在淘汰时的版本
+// 对方持有更新的版本时区间摘要不一致，修复时同一版本的数据不会被拉回
+func (db *EchoDB) rangeEvicted(ranges []KeyRange) []Mutation {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	var versions []Mutation
+	for _, r := range ranges {
+		for it := db.evicted.Seek(r.Start); it.Valid() && r.contains(it.Key()); it.Next() {
+			evicted := it.Value()
+			versions = append(versions, Mutation{Key: it.Key(), Stamp: evicted.Stamp, Clock: evicted.Clock})
+		}
+	}
+	return versions
+}
+
+// AntiEntropy 随机选择一个节点进行一次反熵同步，修复推送时丢失的写入
+// 宕机、网络分区或重启后的节点通过它追上其他节点，而不需要传输全部数据
+func (g *GossipEngine) AntiEntropy() {
+	peers := g.members.peers()
+	if len(peers) == 0 || g.local() == nil || g.members.left() {
+		return
+	}
+
+	peer := peers[rand.IntN(len(peers))]
+	if err := g.syncWith(peer); err != nil {
+		fmt.Printf("Failed to synchronize with node %s: %v\n", peer, err)
+	}
+}
+
+// syncWith 与一个节点进行反熵同步：从整个键空间开始逐层比较 Merkle 树节点的摘要，
+// 只继续拆分摘要不一致的区间，区间足够小时交换其中的数据
+func (g *GossipEngine) syncWith(peer string) error {
+	replica := g.local()
+	ranges := []KeyRange{{}}
+	for len(ranges) > 0 {
+		digests := replica.rangeDigests(ranges)
+		var response DigestResponse
+		if err := postGossip(peer, "/gossip/digest", DigestRequest{NodeID: g.nodeID, Digests: digests}, &response); err != nil {
+			return err
+		}
+
+		// 按两边中较多的键数量决定是否继续拆分，新加入或落后的节点也只按小区间拉取数据
+		var next, leaves []KeyRange
+		for j, i := range response.Mismatched {
+			if i < 0 || i >= len(digests) {
+				continue
+			}
+			local, remote := digests[i].Count, 0
+			if j < len(response.Counts) {
+				remote = response.Counts[j]
+			}
+			if max(local, remote) <= merkleLeafSize {
+				leaves = append(leaves, digests[i].KeyRange)
+				continue
+			}
+
+			var split []KeyRange
+			if remote > local && j < len(response.Splits) {
+				split = response.Splits[j]
+			} else {
+				split = replica.splitRange(digests[i].KeyRange, merkleFanout)
+			}
+			// 无法再拆分的区间直接交换数据，避免重复比较同一个区间
+			if len(split) <= 1 {
+				leaves = append(leaves, digests[i].KeyRange)
+			} else {
+				next = append(next, split...)
+			}
+		}
+
+		for len(leaves) > 0 {
+			n := min(len(leaves), repairBatchRanges)
+			if err := g.repair(peer, replica, leaves[:n]); err != nil {
+				return err
+			}
+			leaves = leaves[n:]
+		}
+		ranges = next
+	}
+	return nil
+}
+
+// repair 与节点交换一组区间内的全部数据，双方各自只应用比本地更新的写入
+func (g *GossipEngine) repair(peer string, replica replica, ranges []KeyRange) error {
+	request := RepairRequest{
+		NodeID:    g.nodeID,
+		Ranges:    ranges,
+		Mutations: replica.rangeMutations(ranges),
+		Evicted:   replica.rangeEvicted(ranges),
+	}
+	var response RepairResponse
+	if err := postGossip(peer, "/gossip/repair", request, &response); err != nil {
+		return err
+	}
+	replica.handleMutations(response.Mutations)
+	return nil
+}
+
+// handleDigest 比较对方发来的区间摘要，返回不一致的区间
+func (g *GossipEngine) handleDigest(c *gin.Context) {
+	var request DigestRequest
+	replica := g.local()
+	if err := c.ShouldBindJSON(&request); err != nil || replica == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid digest request"})
+		return
+	}
+
+	ranges := make([]KeyRange, len(request.Digests))
+	for i, digest := range request.Digests {
+		ranges[i] = digest.KeyRange
+	}
+
+	response := DigestResponse{Mismatched: []int{}, Counts: []int{}, Splits: [][]KeyRange{}}
+	for i, digest := range replica.rangeDigests(ranges) {
+		if digest.Hash == request.Digests[i].Hash && digest.Count == request.Digests[i].Count {
+			continue
+		}
+		response.Mismatched = append(response.Mismatched, i)
+		response.Counts = append(response.Counts, digest.Count)
+
+		var split []KeyRange
+		if digest.Count > merkleLeafSize && digest.Count > request.Digests[i].Count {
+			split = replica.splitRange(digest.KeyRange, merkleFanout)
+		}
+		response.Splits = append(response.Splits, split)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// handleRepair 应用对方发来的区间数据，然后返回对方缺少或比对方更新的数据
+func (g *GossipEngine) handleRepair(c *gin.Context) {
+	var request RepairRequest
+	replica := g.local()
+	if err := c.ShouldBindJSON(&request); err != nil || replica == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repair request"})
+		return
+	}
+
+	replica.handleMutations(request.Mutations)
+
+	// 对方已有相同写入或已经淘汰了相同写入的键不需要再发回去
+	received := make(map[string]Mutation, len(request.Mutations)+len(request.Evicted))
+	for _, mutation := range request.Evicted {
+		received[mutation.Key] = mutation
+	}
+	for _, mutation := range request.Mutations {
+		received[mutation.Key] = mutation
+	}
+	response := RepairResponse{Mutations: []Mutation{}}
+	for _, mutation := range replica.rangeMutations(request.Ranges) {
+		sent, exists := received[mutation.Key]
+		if !exists || sent.Stamp != mutation.Stamp || sent.Clock.compare(mutation.Clock) != clockEqual {
+			response.Mutations = append(response.Mutations, mutation)
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
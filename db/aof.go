@@ -0,0 +1,526 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrCorruptAOF 表示 AOF 文件中间出现了无法解析的记录
+var ErrCorruptAOF = errors.New("append-only file is corrupted")
+
+// AOF 的刷盘策略，对应配置文件中的 aof.fsync
+const (
+	FsyncAlways   = "always"   // 每条记录写入后立即 fsync，最安全也最慢
+	FsyncEverySec = "everysec" // 每秒 fsync 一次，宕机最多丢失约一秒的写入
+	FsyncNo       = "no"       // 只写入操作系统缓存，由操作系统决定何时落盘
+)
+
+// AOF 记录的操作类型
+const (
+	aofSet     = "set"
+	aofDel     = "del"
+	aofExpire  = "expire"
+	aofPersist = "persist"
+	aofHSet    = "hset"
+	aofHDel    = "hdel"
+	aofLPush   = "lpush"
+	aofRPush   = "rpush"
+	aofLPop    = "lpop"
+	aofRPop    = "rpop"
+	aofSAdd    = "sadd"
+	aofSRem    = "srem"
+	aofZAdd    = "zadd"
+	aofZRem    = "zrem"
+	aofMulti   = "multi" // 事务，Batch 中的记录作为一个整体写入和重放
+)
+
+const (
+	aofMagic           = "ECHOAOF2"  // 文件头，末尾的数字是格式版本
+	aofLegacyMagic     = "ECHOAOF1"  // 版本 1 的文件头，其中的值没有类型标签
+	aofFrameHeaderSize = 8           // 每条记录的帧头：4 字节长度 + 4 字节校验和
+	aofMaxRecordSize   = 512 << 20   // 单条记录的长度上限，超过时视为损坏
+	aofSyncInterval    = time.Second // everysec 策略的刷盘间隔
+)
+
+// aofChecksumTable 是记录校验使用的 CRC32-C 表
+var aofChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// aofRecord 是 AOF 中的一条写操作
+// 过期时间记录为绝对时间，重放时不会因为重启而延长键的寿命
+type aofRecord struct {
+	Op       string        `json:"op"`
+	Key      string        `json:"key"`
+	Value    *Value        `json:"value,omitempty"`
+	ExpireAt int64         `json:"expire_at,omitempty"` // Unix 纳秒时间戳，0 表示永不过期
+	Args     []string      `json:"args,omitempty"`      // 集合操作的参数：hset 为字段和值交替排列，其余为字段、元素或成员
+	Count    int           `json:"count,omitempty"`     // lpop、rpop 弹出的元素数量
+	Members  []ZMember     `json:"members,omitempty"`   // zadd 写入的成员和分数
+	Version  uint64        `json:"version,omitempty"`   // 执行这条记录后的版本号
+	Batch    []aofRecord   `json:"batch,omitempty"`     // multi 包含的记录
+	Stamp    *Stamp        `json:"stamp,omitempty"`     // 执行这条记录后键的 HLC 时间戳
+	Clock    VersionVector `json:"clock,omitempty"`     // 执行这条记录后键的版本向量
+	Siblings []Sibling     `json:"siblings,omitempty"`  // 执行这条记录后键的兄弟值
+}
+
+// legacyAOFRecord 是版本 1 的记录格式
+type legacyAOFRecord struct {
+	Op       string          `json:"op"`
+	Key      string          `json:"key"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	ExpireAt int64           `json:"expire_at,omitempty"`
+}
+
+// decodeAOFRecord 按文件版本解析一条记录的负载
+func decodeAOFRecord(payload []byte, legacy bool) (aofRecord, error) {
+	if !legacy {
+		var record aofRecord
+		err := json.Unmarshal(payload, &record)
+		return record, err
+	}
+
+	var old legacyAOFRecord
+	if err := json.Unmarshal(payload, &old); err != nil {
+		return aofRecord{}, err
+	}
+	record := aofRecord{Op: old.Op, Key: old.Key, ExpireAt: old.ExpireAt}
+	if old.Value != nil {
+		value := legacyValue(old.Value)
+		record.Value = &value
+	}
+	return record, nil
+}
+
+// encodeExpiration 把过期时间转换为 Unix 纳秒时间戳，零值表示永不过期
+func encodeExpiration(expiration time.Time) int64 {
+	if expiration.IsZero() {
+		return 0
+	}
+	return expiration.UnixNano()
+}
+
+// decodeExpiration 是 encodeExpiration 的逆操作
+func decodeExpiration(expireAt int64) time.Time {
+	if expireAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, expireAt)
+}
+
+// encodeAOFRecord 把记录编码为一帧：大端序的负载长度、负载的 CRC32-C 校验和，以及 JSON 负载
+func encodeAOFRecord(record aofRecord) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("encode AOF record: %w", err)
+	}
+
+	frame := make([]byte, aofFrameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, aofChecksumTable))
+	copy(frame[aofFrameHeaderSize:], payload)
+	return frame, nil
+}
+
+// replayAOF 依次读取 AOF 中的记录并交给 apply 处理，返回最后一条完整记录结束处的偏移量，
+// 以及文件是否为需要升级的旧版本格式
+// 文件不存在时视为空文件。崩溃可能在文件末尾留下不完整的记录，这种情况下停止读取，
+// 由调用方截断尾部；文件中间的记录损坏则返回 ErrCorruptAOF
+func replayAOF(path string, apply func(aofRecord) error) (offset int64, legacy bool, err error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+	fileSize := info.Size()
+	reader := bufio.NewReader(file)
+
+	// 文件头不完整说明创建文件时就发生了崩溃，其中不可能有记录
+	magic := make([]byte, len(aofMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	switch string(magic) {
+	case aofMagic:
+	case aofLegacyMagic:
+		legacy = true
+	default:
+		return 0, false, fmt.Errorf("%w: unknown file header %q", ErrCorruptAOF, magic)
+	}
+
+	offset = int64(len(aofMagic))
+	header := make([]byte, aofFrameHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return offset, legacy, nil
+			}
+			return offset, legacy, err
+		}
+
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		checksum := binary.BigEndian.Uint32(header[4:8])
+		end := offset + aofFrameHeaderSize + length
+
+		// 声明的长度超出文件末尾，说明最后一条记录没有写完
+		if end > fileSize {
+			return offset, legacy, nil
+		}
+		if length > aofMaxRecordSize {
+			return offset, legacy, fmt.Errorf("%w: record of %d bytes at offset %d", ErrCorruptAOF, length, offset)
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return offset, legacy, err
+		}
+		if crc32.Checksum(payload, aofChecksumTable) != checksum {
+			// 只有最后一条记录校验失败时才视为写入中断
+			if end == fileSize {
+				return offset, legacy, nil
+			}
+			return offset, legacy, fmt.Errorf("%w: checksum mismatch at offset %d", ErrCorruptAOF, offset)
+		}
+
+		record, err := decodeAOFRecord(payload, legacy)
+		if err != nil {
+			return offset, legacy, fmt.Errorf("%w: invalid record at offset %d: %v", ErrCorruptAOF, offset, err)
+		}
+		if err := apply(record); err != nil {
+			return offset, legacy, fmt.Errorf("replay record at offset %d: %w", offset, err)
+		}
+		offset = end
+	}
+}
+
+// aofWriter 负责把记录追加到 AOF 并按策略刷盘
+type aofWriter struct {
+	mutex    sync.Mutex
+	path     string
+	file     *os.File
+	fsync    string
+	size     int64 // 当前文件的长度
+	baseSize int64 // 启动或上次重写完成时的文件长度，用于判断是否需要重写
+	dirty    bool  // 是否有尚未 fsync 的写入
+	closed   bool
+	done     chan struct{}
+
+	rewriting     bool     // 是否正在重写
+	rewriteBuffer [][]byte // 重写期间追加的记录，重写完成时补写到新文件末尾
+}
+
+// openAOFWriter 打开 AOF 用于追加，validSize 之后的不完整数据会被截断
+func openAOFWriter(path, fsync string, validSize int64) (*aofWriter, error) {
+	switch fsync {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+	default:
+		return nil, fmt.Errorf("unknown AOF fsync policy %q", fsync)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() != validSize {
+		log.Printf("AOF %s: truncating %d bytes of incomplete data at offset %d", path, info.Size()-validSize, validSize)
+		if err := file.Truncate(validSize); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	// 新文件先写入文件头
+	if validSize == 0 {
+		if _, err := file.WriteAt([]byte(aofMagic), 0); err != nil {
+			file.Close()
+			return nil, err
+		}
+		validSize = int64(len(aofMagic))
+	}
+	if _, err := file.Seek(validSize, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	writer := &aofWriter{
+		path:     path,
+		file:     file,
+		fsync:    fsync,
+		size:     validSize,
+		baseSize: validSize,
+		done:     make(chan struct{}),
+	}
+	if fsync == FsyncEverySec {
+		go writer.syncLoop()
+	}
+	return writer, nil
+}
+
+// append 追加一条记录，always 策略下返回前会完成 fsync
+func (w *aofWriter) append(record aofRecord) error {
+	frame, err := encodeAOFRecord(record)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	n, err := w.file.Write(frame)
+	if err != nil {
+		// 截掉只写入了一部分的记录，否则之后追加的记录跟在损坏的数据后面，重放时会在文件中间遇到损坏而无法启动
+		if n > 0 {
+			if truncateErr := w.truncate(w.size); truncateErr != nil {
+				w.size += int64(n)
+				return fmt.Errorf("write AOF: %w (truncate partial record: %v)", err, truncateErr)
+			}
+		}
+		return fmt.Errorf("write AOF: %w", err)
+	}
+	w.size += int64(n)
+	if w.rewriting {
+		w.rewriteBuffer = append(w.rewriteBuffer, frame)
+	}
+
+	switch w.fsync {
+	case FsyncAlways:
+		if err := w.file.Sync(); err != nil {
+			return fmt.Errorf("fsync AOF: %w", err)
+		}
+	case FsyncEverySec:
+		w.dirty = true
+	}
+	return nil
+}
+
+// truncate 把文件截断到 size 并把写入位置移到文件末尾，调用方必须持有 w.mutex
+func (w *aofWriter) truncate(size int64) error {
+	if err := w.file.Truncate(size); err != nil {
+		return err
+	}
+	_, err := w.file.Seek(size, io.SeekStart)
+	return err
+}
+
+// syncLoop 是 everysec 策略的后台刷盘任务
+func (w *aofWriter) syncLoop() {
+	ticker := time.NewTicker(aofSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.mutex.Lock()
+			if w.dirty {
+				if err := w.file.Sync(); err != nil {
+					log.Printf("fsync AOF: %v", err)
+				} else {
+					w.dirty = false
+				}
+			}
+			w.mutex.Unlock()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// close 停止后台刷盘，把剩余数据落盘后关闭文件
+func (w *aofWriter) close() error {
+	if w.fsync == FsyncEverySec {
+		close(w.done)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.closed = true
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// loadAOF 重放 AOF 恢复数据，然后打开它继续追加新的写操作
+// 重放发生在 NewEchoDB 返回之前，此时还没有其他协程访问数据库
+func (db *EchoDB) loadAOF() error {
+	path, now := db.config.AOF.Filename, time.Now()
+
+	validSize, legacy, err := replayAOF(path, func(record aofRecord) error {
+		return db.replayRecord(record, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	writer, err := openAOFWriter(path, db.config.AOF.Fsync, validSize)
+	if err != nil {
+		return err
+	}
+	db.aof = writer
+
+	// 旧版本的文件不能继续追加新格式的记录，立即重写为当前格式
+	if legacy {
+		log.Printf("AOF %s: upgrading from an older format", path)
+		return db.RewriteAOF()
+	}
+	return nil
+}
+
+// replayRecord 重放一条 AOF 记录并恢复其中的版本号和复制状态，事务中的记录依次重放
+func (db *EchoDB) replayRecord(record aofRecord, now time.Time) error {
+	if record.Op == aofMulti {
+		for _, child := range record.Batch {
+			if err := db.replayRecord(child, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := db.applyRecord(record, now); err != nil {
+		return err
+	}
+	db.restoreVersion(record.Key, record.Version)
+	db.restoreReplica(record)
+	return nil
+}
+
+// applyRecord 在内存中执行一条 AOF 记录，重放时已过期的键直接丢弃
+func (db *EchoDB) applyRecord(record aofRecord, now time.Time) error {
+	expiration := decodeExpiration(record.ExpireAt)
+	expired := !expiration.IsZero() && !now.Before(expiration)
+
+	switch record.Op {
+	case aofSet:
+		if record.Value == nil {
+			return fmt.Errorf("%w: set without value", ErrCorruptAOF)
+		}
+		if expired {
+			db.removeKey(record.Key)
+			return nil
+		}
+		return db.set(record.Key, *record.Value, expiration, now)
+	case aofDel:
+		db.removeKey(record.Key)
+	case aofExpire:
+		if item, exists := db.data.Get(record.Key); exists {
+			if expired {
+				db.removeKey(record.Key)
+			} else {
+				db.setExpiration(record.Key, item, expiration)
+			}
+		}
+	case aofPersist:
+		if item, exists := db.data.Get(record.Key); exists {
+			db.setExpiration(record.Key, item, time.Time{})
+		}
+	case aofHSet:
+		if len(record.Args)%2 != 0 {
+			return fmt.Errorf("%w: hset with an odd number of arguments", ErrCorruptAOF)
+		}
+		fields := make(map[string]string, len(record.Args)/2)
+		for i := 0; i < len(record.Args); i += 2 {
+			fields[record.Args[i]] = record.Args[i+1]
+		}
+		_, _, err := db.hset(record.Key, fields, now)
+		return err
+	case aofHDel:
+		_, err := db.hdel(record.Key, record.Args, now)
+		return err
+	case aofLPush, aofRPush:
+		_, err := db.push(record.Key, record.Args, record.Op == aofLPush, now)
+		return err
+	case aofLPop, aofRPop:
+		_, err := db.pop(record.Key, record.Count, record.Op == aofLPop, now)
+		return err
+	case aofSAdd:
+		_, err := db.sadd(record.Key, record.Args, now)
+		return err
+	case aofSRem:
+		_, err := db.srem(record.Key, record.Args, now)
+		return err
+	case aofZAdd:
+		_, _, err := db.zadd(record.Key, record.Members, now)
+		return err
+	case aofZRem:
+		_, err := db.zrem(record.Key, record.Args, now)
+		return err
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrCorruptAOF, record.Op)
+	}
+	return nil
+}
+
+// appendLog 记录一次本地的写操作：为键生成新的时间戳和版本向量并复制给其他节点，然后追加到 AOF
+// 调用方必须持有写锁
+func (db *EchoDB) appendLog(record aofRecord) error {
+	stamp, clock := db.replicate(record.Key, time.Now())
+	record.Stamp, record.Clock = &stamp, clock
+	return db.writeLog(record)
+}
+
+// writeLog 把一条记录追加到 AOF，未开启 AOF 时什么也不做，调用方必须持有写锁
+func (db *EchoDB) writeLog(record aofRecord) error {
+	if db.aof == nil {
+		return nil
+	}
+	record.Version = db.version
+	// 事务执行期间先收集记录，EXEC 结束后一起写入
+	if db.txLog != nil {
+		*db.txLog = append(*db.txLog, record)
+		return nil
+	}
+	return db.aof.append(record)
+}
+
+// propagateDelete 记录一次由过期或淘汰引起的删除
+// 这类删除发生在后台或读操作中，写入失败时只能记录日志
+// 过期时间会随写入一起复制，其他节点自行过期；淘汰只影响本节点，因此都不复制给其他节点
+func (db *EchoDB) propagateDelete(key string) {
+	if err := db.writeLog(aofRecord{Op: aofDel, Key: key}); err != nil {
+		log.Printf("AOF: %v", err)
+	}
+}
+
+// Close 把 AOF 中尚未落盘的数据写入磁盘并关闭文件
+func (db *EchoDB) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.aof == nil {
+		return nil
+	}
+	err := db.aof.close()
+	db.aof = nil
+	return err
+}
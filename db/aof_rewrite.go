@@ -0,0 +1,277 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrAOFDisabled 表示未开启 AOF
+var ErrAOFDisabled = errors.New("append-only file is disabled")
+
+// ErrRewriteInProgress 表示已有一次 AOF 重写正在进行
+var ErrRewriteInProgress = errors.New("AOF rewrite already in progress")
+
+// aofRewriteCheckInterval 是检查 AOF 是否需要自动重写的间隔
+const aofRewriteCheckInterval = time.Second
+
+// AOFStatus 是 AOF 的状态
+type AOFStatus struct {
+	Enabled          bool      `json:"enabled"`                      // 是否开启 AOF
+	Size             int64     `json:"size"`                         // 当前文件的长度
+	BaseSize         int64     `json:"base_size"`                    // 启动或上次重写完成时的文件长度
+	Rewriting        bool      `json:"rewriting"`                    // 是否正在重写
+	LastRewriteTime  time.Time `json:"last_rewrite_time"`            // 最近一次重写完成的时间
+	LastRewriteError string    `json:"last_rewrite_error,omitempty"` // 最近一次重写失败的原因，成功后清空
+}
+
+// beginRewrite 开始缓冲新追加的记录，已有重写正在进行时返回 false
+func (w *aofWriter) beginRewrite() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.rewriting || w.closed {
+		return false
+	}
+	w.rewriting = true
+	w.rewriteBuffer = nil
+	return true
+}
+
+// abortRewrite 放弃本次重写，丢弃缓冲的记录
+func (w *aofWriter) abortRewrite() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.rewriting = false
+	w.rewriteBuffer = nil
+}
+
+// finishRewrite 把重写期间缓冲的记录补写到新文件，然后用新文件原子地替换当前文件
+// 补写和替换期间持有写入锁，新的追加会短暂等待，但不会丢失
+func (w *aofWriter) finishRewrite(temp *os.File) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	defer func() {
+		w.rewriting = false
+		w.rewriteBuffer = nil
+	}()
+
+	if w.closed {
+		return errors.New("append-only file was closed during rewrite")
+	}
+	for _, frame := range w.rewriteBuffer {
+		if _, err := temp.Write(frame); err != nil {
+			return err
+		}
+	}
+	if err := temp.Sync(); err != nil {
+		return err
+	}
+
+	size, err := temp.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), w.path); err != nil {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(w.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	// 重命名后临时文件的句柄指向的就是新的 AOF，直接用它继续追加
+	if err := w.file.Close(); err != nil {
+		log.Printf("close old AOF: %v", err)
+	}
+	w.file = temp
+	w.size = size
+	w.baseSize = size
+	w.dirty = false
+	return nil
+}
+
+// shouldRewrite 判断文件相对上次重写的增长是否达到了自动重写的阈值
+// percentage 不为正数时不自动重写
+func (w *aofWriter) shouldRewrite(percentage int, minSize int64) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if percentage <= 0 || w.rewriting || w.closed || w.size < minSize {
+		return false
+	}
+	growth := (w.size - w.baseSize) * 100 / max(w.baseSize, 1)
+	return growth >= int64(percentage)
+}
+
+// RewriteAOF 同步重写 AOF
+func (db *EchoDB) RewriteAOF() error {
+	writer, records, err := db.beginAOFRewrite()
+	if err != nil {
+		return err
+	}
+	return db.rewriteAOF(writer, records)
+}
+
+// BackgroundRewriteAOF 在后台重写 AOF，重写结果可以通过 AOFStatus 查询
+func (db *EchoDB) BackgroundRewriteAOF() error {
+	writer, records, err := db.beginAOFRewrite()
+	if err != nil {
+		return err
+	}
+	go db.rewriteAOF(writer, records)
+	return nil
+}
+
+// AOFStatus 返回 AOF 的状态
+func (db *EchoDB) AOFStatus() AOFStatus {
+	db.mutex.RLock()
+	writer := db.aof
+	db.mutex.RUnlock()
+
+	db.aofMutex.Lock()
+	status := AOFStatus{
+		LastRewriteTime:  db.lastRewriteTime,
+		LastRewriteError: db.lastRewriteError,
+	}
+	db.aofMutex.Unlock()
+
+	if writer == nil {
+		return status
+	}
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	status.Enabled = !writer.closed
+	status.Size = writer.size
+	status.BaseSize = writer.baseSize
+	status.Rewriting = writer.rewriting
+	return status
+}
+
+// beginAOFRewrite 生成重写后的日志记录，同时让 AOF 开始缓冲之后的写入
+// 两者在同一把读锁内完成，因此重写的记录加上缓冲的记录恰好等于重写完成时的数据
+func (db *EchoDB) beginAOFRewrite() (*aofWriter, []aofRecord, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.aof == nil {
+		return nil, nil, ErrAOFDisabled
+	}
+	if !db.aof.beginRewrite() {
+		return nil, nil, ErrRewriteInProgress
+	}
+	return db.aof, db.rewriteRecords(time.Now()), nil
+}
+
+// rewriteRecords 生成最精简的日志记录：每个键一条 set 记录，每个删除标记一条 del 记录
+// 调用方必须持有读锁
+func (db *EchoDB) rewriteRecords(now time.Time) []aofRecord {
+	entries := db.copyEntries(now)
+	records := make([]aofRecord, 0, len(entries)+len(db.tombstones))
+	for _, entry := range entries {
+		stamp := entry.Stamp
+		records = append(records, aofRecord{
+			Op:       aofSet,
+			Key:      entry.Key,
+			Value:    &entry.Value,
+			ExpireAt: encodeExpiration(entry.Expiration),
+			Version:  entry.Version,
+			Stamp:    &stamp,
+			Clock:    entry.Clock,
+			Siblings: entry.Siblings,
+		})
+	}
+	// 保留删除标记，重启后仍能拒绝较旧的复制写入
+	for key, tombstone := range db.tombstones {
+		stamp := tombstone.Stamp
+		records = append(records, aofRecord{Op: aofDel, Key: key, Stamp: &stamp, Clock: tombstone.Clock})
+	}
+	return records
+}
+
+// rewriteAOF 把重写的记录写为新的日志，然后替换当前的 AOF
+func (db *EchoDB) rewriteAOF(writer *aofWriter, records []aofRecord) error {
+	err := writeAOFRewrite(writer, records)
+
+	db.aofMutex.Lock()
+	defer db.aofMutex.Unlock()
+
+	if err != nil {
+		db.lastRewriteError = err.Error()
+		log.Printf("rewrite AOF: %v", err)
+		return err
+	}
+	db.lastRewriteTime = time.Now()
+	db.lastRewriteError = ""
+	return nil
+}
+
+// writeAOFRewrite 在 AOF 所在目录写入新日志并替换当前文件，失败时保留原文件
+func writeAOFRewrite(writer *aofWriter, records []aofRecord) error {
+	temp, err := os.CreateTemp(filepath.Dir(writer.path), filepath.Base(writer.path)+".rewrite-*")
+	if err != nil {
+		writer.abortRewrite()
+		return err
+	}
+	temp.Chmod(0o644)
+
+	swapped := false
+	defer func() {
+		if !swapped {
+			writer.abortRewrite()
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err := temp.WriteString(aofMagic); err != nil {
+		return err
+	}
+	for _, record := range records {
+		frame, err := encodeAOFRecord(record)
+		if err != nil {
+			return err
+		}
+		if _, err := temp.Write(frame); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.finishRewrite(temp); err != nil {
+		return fmt.Errorf("swap AOF: %w", err)
+	}
+	swapped = true
+	return nil
+}
+
+// startAOFRewriteProcess 定期检查 AOF 的增长，超过配置的阈值时在后台重写
+func (db *EchoDB) startAOFRewriteProcess() {
+	ticker := time.NewTicker(aofRewriteCheckInterval)
+	defer ticker.Stop()
+
+	percentage := db.config.AOF.RewritePercentage
+	minSize := db.config.AOF.RewriteMinSize
+	for range ticker.C {
+		db.mutex.RLock()
+		writer := db.aof
+		db.mutex.RUnlock()
+
+		if writer == nil {
+			return
+		}
+		if !writer.shouldRewrite(percentage, minSize) {
+			continue
+		}
+		if err := db.BackgroundRewriteAOF(); err != nil && !errors.Is(err, ErrRewriteInProgress) {
+			log.Printf("schedule AOF rewrite: %v", err)
+		}
+	}
+}
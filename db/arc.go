@@ -0,0 +1,138 @@
+package db
+
+import "container/list"
+
+// ARC 中键所在的链表
+const (
+	arcT1 = iota // 最近只访问过一次的键
+	arcT2        // 最近访问过多次的键
+	arcB1        // 从 T1 淘汰的键的幽灵记录
+	arcB2        // 从 T2 淘汰的键的幽灵记录
+)
+
+// arcEntry 是 ARC 策略中的一个键
+type arcEntry struct {
+	key  string
+	list int
+}
+
+// arcPolicy 是自适应替换缓存（ARC）淘汰策略
+// T1、T2 保存在内存中的键，B1、B2 只记录最近被淘汰的键名。
+// 命中 B1 说明偏重近期访问的 T1 太小，命中 B2 说明偏重频繁访问的 T2 太小，
+// 目标值 target 据此在两者之间自适应调整，淘汰时优先从超出目标大小的一侧选取
+type arcPolicy struct {
+	lists   [4]*list.List
+	entries map[string]*list.Element
+	target  int    // T1 的目标大小
+	victim  string // 最近一次 Victim 选出的键，删除时据此写入幽灵链表
+}
+
+func newARCPolicy() *arcPolicy {
+	policy := &arcPolicy{entries: make(map[string]*list.Element)}
+	for i := range policy.lists {
+		policy.lists[i] = list.New()
+	}
+	return policy
+}
+
+// capacity 返回当前缓存的容量，即内存中键的数量
+func (p *arcPolicy) capacity() int {
+	return p.lists[arcT1].Len() + p.lists[arcT2].Len()
+}
+
+// move 把键移动到目标链表的最前端
+func (p *arcPolicy) move(element *list.Element, target int) {
+	entry := element.Value.(*arcEntry)
+	p.lists[entry.list].Remove(element)
+	entry.list = target
+	p.entries[entry.key] = p.lists[target].PushFront(entry)
+}
+
+// remove 彻底移除一个键
+func (p *arcPolicy) remove(element *list.Element) {
+	entry := element.Value.(*arcEntry)
+	p.lists[entry.list].Remove(element)
+	delete(p.entries, entry.key)
+}
+
+// trimGhosts 让每个幽灵链表都不超过缓存容量
+func (p *arcPolicy) trimGhosts() {
+	limit := p.capacity()
+	for _, ghost := range []int{arcB1, arcB2} {
+		for p.lists[ghost].Len() > limit {
+			p.remove(p.lists[ghost].Back())
+		}
+	}
+}
+
+func (p *arcPolicy) OnInsert(key string, item *Item) {
+	element, exists := p.entries[key]
+	if !exists {
+		p.entries[key] = p.lists[arcT1].PushFront(&arcEntry{key: key, list: arcT1})
+		return
+	}
+
+	b1, b2 := p.lists[arcB1].Len(), p.lists[arcB2].Len()
+	switch element.Value.(*arcEntry).list {
+	case arcB1:
+		// 最近淘汰的 T1 键被再次写入，增大 T1 的目标大小
+		p.target = min(p.capacity()+1, p.target+max(b2/b1, 1))
+		p.move(element, arcT2)
+	case arcB2:
+		// 最近淘汰的 T2 键被再次写入，减小 T1 的目标大小
+		p.target = max(0, p.target-max(b1/b2, 1))
+		p.move(element, arcT2)
+	default:
+		p.move(element, arcT2)
+	}
+}
+
+func (p *arcPolicy) OnAccess(key string, item *Item) {
+	element, exists := p.entries[key]
+	if !exists {
+		return
+	}
+
+	// 内存中的键再次被访问，移动到 T2 的最前端
+	if current := element.Value.(*arcEntry).list; current == arcT1 || current == arcT2 {
+		p.move(element, arcT2)
+	}
+}
+
+func (p *arcPolicy) OnDelete(key string) {
+	element, exists := p.entries[key]
+	if !exists {
+		return
+	}
+
+	// 被淘汰的键留下幽灵记录，主动删除和过期的键直接移除
+	if key == p.victim {
+		p.victim = ""
+		if element.Value.(*arcEntry).list == arcT1 {
+			p.move(element, arcB1)
+		} else {
+			p.move(element, arcB2)
+		}
+		p.trimGhosts()
+		return
+	}
+
+	p.remove(element)
+}
+
+func (p *arcPolicy) Victim() (string, bool) {
+	t1, t2 := p.lists[arcT1], p.lists[arcT2]
+
+	var element *list.Element
+	if t1.Len() > 0 && (t1.Len() > p.target || t2.Len() == 0) {
+		element = t1.Back()
+	} else {
+		element = t2.Back()
+	}
+	if element == nil {
+		return "", false
+	}
+
+	p.victim = element.Value.(*arcEntry).key
+	return p.victim, true
+}
@@ -0,0 +1,77 @@
+package db
+
+import "time"
+
+// BatchEntry 是批量写入中的一个键值
+type BatchEntry struct {
+	Key   string
+	Value Value
+	TTL   time.Duration // 过期时间，0 表示永不过期
+}
+
+// MGet 批量查询数据，只获取一次锁，返回与 keys 一一对应的数据项副本，不存在的键对应 nil
+func (db *EchoDB) MGet(keys ...string) []*Item {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	now := time.Now()
+	items := make([]*Item, len(keys))
+	for i, key := range keys {
+		item, exists := db.lookup(key, now)
+		if !exists {
+			continue
+		}
+		db.touch(key, item, now)
+		items[i] = item.clone()
+	}
+	return items
+}
+
+// MSet 批量写入数据，只获取一次锁，返回与 entries 一一对应的错误
+// 各个键独立写入，部分键失败时其他键仍然会被写入
+func (db *EchoDB) MSet(entries []BatchEntry) []error {
+	errs := make([]error, len(entries))
+	for i, entry := range entries {
+		if entry.TTL < 0 {
+			errs[i] = ErrInvalidTTL
+		} else {
+			errs[i] = entry.Value.validate()
+		}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	now := time.Now()
+	for i, entry := range entries {
+		if errs[i] == nil {
+			errs[i] = db.store(entry.Key, entry.Value, expirationFor(now, entry.TTL), now)
+		}
+	}
+	return errs
+}
+
+// MDelete 批量删除数据，只获取一次锁，返回与 keys 一一对应的错误，不存在的键对应 ErrKeyNotFound
+func (db *EchoDB) MDelete(keys ...string) []error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	errs := make([]error, len(keys))
+	if err := db.writable(); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	now := time.Now()
+	for i, key := range keys {
+		errs[i] = db.del(key, now)
+	}
+	return errs
+}
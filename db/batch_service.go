@@ -0,0 +1,147 @@
+package db
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// KeysRequest 定义批量查询或删除的请求体
+type KeysRequest struct {
+	Keys []string `json:"keys" binding:"required"`
+}
+
+// BatchSetRequest 定义批量写入的请求体
+type BatchSetRequest struct {
+	Entries []BatchSetEntry `json:"entries" binding:"required"`
+}
+
+// BatchSetEntry 定义批量写入中的一个键值，字段含义与 PutRequest 相同
+type BatchSetEntry struct {
+	Key string `json:"key"`
+	PutRequest
+}
+
+// BatchResult 定义批量操作中一个键的结果
+type BatchResult struct {
+	Key   string `json:"key"`
+	Item  *Item  `json:"item,omitempty"`  // 批量查询时键的数据
+	Error string `json:"error,omitempty"` // 该键失败的原因，成功时为空
+}
+
+// BatchData 定义批量操作的返回数据
+type BatchData struct {
+	Results []BatchResult `json:"results"` // 与请求中的键一一对应
+	Failed  int           `json:"failed"`  // 失败的键的数量
+}
+
+// newBatchData 根据每个键的错误生成批量操作的返回数据
+func newBatchData(keys []string, errs []error) BatchData {
+	data := BatchData{Results: make([]BatchResult, len(keys))}
+	for i, key := range keys {
+		data.Results[i].Key = key
+		if errs[i] != nil {
+			data.Results[i].Error = errs[i].Error()
+			data.Failed++
+		}
+	}
+	return data
+}
+
+// BatchGet 批量查询键值
+// @Summary 批量查询
+// @Description 一次查询多个键，结果与请求中的键一一对应，不存在的键在 error 中说明。
+// @Tags batch
+// @Accept  json
+// @Produce  json
+// @Param body body KeysRequest true "查询的键"
+// @Success 200 {object} KVResponse "查询完成，data 为 BatchData"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Router /batch/get [post]
+func (db *EchoDB) BatchGet(context *gin.Context) {
+	var request KeysRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	items := db.MGet(request.Keys...)
+	errs := make([]error, len(items))
+	for i, item := range items {
+		if item == nil {
+			errs[i] = ErrKeyNotFound
+		}
+	}
+
+	data := newBatchData(request.Keys, errs)
+	for i, item := range items {
+		data.Results[i].Item = item
+	}
+	respondSuccess(context, data)
+}
+
+// BatchSet 批量写入键值
+// @Summary 批量写入
+// @Description 一次写入多个键，各个键独立写入，部分键失败时其他键仍会写入，失败原因在对应结果的 error 中说明。
+// @Tags batch
+// @Accept  json
+// @Produce  json
+// @Param body body BatchSetRequest true "写入的键值"
+// @Success 200 {object} KVResponse "写入完成，data 为 BatchData"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Router /batch/set [post]
+func (db *EchoDB) BatchSet(context *gin.Context) {
+	var request BatchSetRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	keys := make([]string, len(request.Entries))
+	errs := make([]error, len(request.Entries))
+
+	// 无法解析的值不参与写入，positions 记录参与写入的键在请求中的位置
+	var entries []BatchEntry
+	var positions []int
+	for i, entry := range request.Entries {
+		keys[i] = entry.Key
+		value, err := entry.value()
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		ttl := db.lifetime
+		if entry.TTL != nil {
+			if ttl, err = ttlSeconds(*entry.TTL); err != nil {
+				errs[i] = err
+				continue
+			}
+		}
+		entries = append(entries, BatchEntry{Key: entry.Key, Value: value, TTL: ttl})
+		positions = append(positions, i)
+	}
+
+	for j, err := range db.MSet(entries) {
+		errs[positions[j]] = err
+	}
+	respondSuccess(context, newBatchData(keys, errs))
+}
+
+// BatchDelete 批量删除键
+// @Summary 批量删除
+// @Description 一次删除多个键，结果与请求中的键一一对应，不存在的键在 error 中说明。
+// @Tags batch
+// @Accept  json
+// @Produce  json
+// @Param body body KeysRequest true "删除的键"
+// @Success 200 {object} KVResponse "删除完成，data 为 BatchData"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Router /batch/delete [post]
+func (db *EchoDB) BatchDelete(context *gin.Context) {
+	var request KeysRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	respondSuccess(context, newBatchData(request.Keys, db.MDelete(request.Keys...)))
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"maps"
+	"time"
+)
+
+// Stamp 是一次写入的混合逻辑时钟（HLC）时间戳
+// 节点间按 Stamp 的先后决定并发写入的胜者：依次比较物理时间、逻辑计数和节点 ID
+type Stamp struct {
+	Time    int64  `json:"time"`              // 物理时间部分，Unix 纳秒时间戳
+	Logical uint32 `json:"logical,omitempty"` // 逻辑计数，物理时间相同或回拨时递增
+	Node    string `json:"node"`              // 执行写入的节点
+}
+
+// After 判断 s 是否比 other 更新
+func (s Stamp) After(other Stamp) bool {
+	if s.Time != other.Time {
+		return s.Time > other.Time
+	}
+	if s.Logical != other.Logical {
+		return s.Logical > other.Logical
+	}
+	return s.Node > other.Node
+}
+
+// hybridClock 是本节点的混合逻辑时钟，生成的时间戳严格递增，并且大于所有已观察到的时间戳
+// 因此因果上在后的写入总是得到更大的时间戳，即使节点之间的物理时钟存在偏差
+// 调用方必须持有写锁
+type hybridClock struct {
+	time    int64
+	logical uint32
+}
+
+// now 为本地的写入生成新的时间戳
+func (c *hybridClock) now(physical time.Time, node string) Stamp {
+	if wall := physical.UnixNano(); wall > c.time {
+		c.time, c.logical = wall, 0
+	} else {
+		c.logical++
+	}
+	return Stamp{Time: c.time, Logical: c.logical, Node: node}
+}
+
+// observe 记录收到或恢复的时间戳，之后生成的时间戳都大于它
+func (c *hybridClock) observe(stamp Stamp) {
+	if stamp.Time > c.time || (stamp.Time == c.time && stamp.Logical > c.logical) {
+		c.time, c.logical = stamp.Time, stamp.Logical
+	}
+}
+
+// clockOrder 是两个版本向量之间的因果关系
+type clockOrder int
+
+const (
+	clockEqual      clockOrder = iota // 两者相同
+	clockBefore                       // 前者发生在后者之前
+	clockAfter                        // 前者发生在后者之后
+	clockConcurrent                   // 两者并发，互不包含
+)
+
+// VersionVector 记录一个键在每个节点上被写入的次数，用于判断两次写入之间的因果关系
+// 版本向量创建后不再修改，increment 和 merge 都返回新的向量
+type VersionVector map[string]uint64
+
+// increment 返回在节点 node 上写入一次之后的版本向量
+func (v VersionVector) increment(node string) VersionVector {
+	result := maps.Clone(v)
+	if result == nil {
+		result = make(VersionVector, 1)
+	}
+	result[node]++
+	return result
+}
+
+// merge 返回同时包含 v 和 other 中所有写入的最小版本向量
+func (v VersionVector) merge(other VersionVector) VersionVector {
+	result := maps.Clone(v)
+	if result == nil {
+		result = make(VersionVector, len(other))
+	}
+	for node, count := range other {
+		result[node] = max(result[node], count)
+	}
+	return result
+}
+
+// compare 判断 v 与 other 的因果关系
+func (v VersionVector) compare(other VersionVector) clockOrder {
+	less, greater := false, false
+	for node, count := range v {
+		if count > other[node] {
+			greater = true
+		}
+	}
+	for node, count := range other {
+		if count > v[node] {
+			less = true
+		}
+	}
+
+	switch {
+	case less && greater:
+		return clockConcurrent
+	case less:
+		return clockBefore
+	case greater:
+		return clockAfter
+	default:
+		return clockEqual
+	}
+}
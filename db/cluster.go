@@ -0,0 +1,126 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// leaveAnnounceTimeout 是离开时向每个成员宣告离开的超时时间
+const leaveAnnounceTimeout = time.Second
+
+// ErrJoinFailed 表示所有种子节点都无法访问
+var ErrJoinFailed = errors.New("no seed node could be reached")
+
+// ErrHandoffFailed 表示离开前没有任何成员能够接收本节点的数据
+var ErrHandoffFailed = errors.New("no member could take over the data")
+
+// ErrNodeLeft 表示本节点已经离开集群，写入不会再复制给其他节点，因此被拒绝
+var ErrNodeLeft = errors.New("node has left the cluster")
+
+// JoinRequest 是新节点向种子节点发送的加入请求
+type JoinRequest struct {
+	From MemberUpdate `json:"from"` // 新节点自己的状态
+}
+
+// JoinResponse 返回种子节点已知的成员，包括种子节点自己
+type JoinResponse struct {
+	Members []MemberUpdate `json:"members"`
+}
+
+// Join 通过种子节点加入集群：向每个种子节点发送加入请求并合并它返回的成员列表，
+// 种子节点把新节点的加入捎带在探测消息上宣告给集群，返回成功访问的种子节点数量
+// 离开集群后调用 Join 可以重新加入
+func (g *GossipEngine) Join(seeds []string) (int, error) {
+	g.members.rejoin()
+
+	self := g.members.local()
+	joined, attempted := 0, 0
+	for _, seed := range seeds {
+		if seed == self.Address {
+			continue
+		}
+		attempted++
+
+		var response JoinResponse
+		if err := postGossip(seed, "/gossip/join", JoinRequest{From: g.members.local()}, &response); err != nil {
+			fmt.Printf("Failed to join through seed %s: %v\n", seed, err)
+			continue
+		}
+		g.receive(response.Members)
+		joined++
+	}
+
+	if attempted > 0 && joined == 0 {
+		return 0, ErrJoinFailed
+	}
+	return joined, nil
+}
+
+// Leave 优雅地离开集群：先发送积压的变更，并与一个成员完成反熵同步，把本节点的数据交给它，
+// 然后标记离开，发送交接期间的写入并宣告离开。离开后本节点拒绝写入，不再探测、复制和同步，可以安全地关闭
+// 没有成员能够接收数据时返回 ErrHandoffFailed，此时本节点仍留在集群中
+func (g *GossipEngine) Leave() error {
+	if g.members.left() {
+		return nil
+	}
+
+	peers := g.members.peers()
+	if len(peers) > 0 {
+		g.Gossip()
+		if err := g.handoff(peers); err != nil {
+			return err
+		}
+	}
+
+	// 标记离开与本地写入互斥：此前的写入都已加入发送队列，此后的写入返回 ErrNodeLeft
+	if replica := g.local(); replica != nil {
+		replica.exclusive(g.members.leave)
+	} else {
+		g.members.leave()
+	}
+	g.flush()
+
+	for _, peer := range peers {
+		if _, err := g.ping(peer, "", leaveAnnounceTimeout); err != nil {
+			fmt.Printf("Failed to announce leave to node %s: %v\n", peer, err)
+		}
+	}
+	return nil
+}
+
+// handoff 按随机顺序尝试与成员进行反熵同步，直到一个成员拥有本节点的全部数据
+// 接收数据的成员会把新的写入继续转发给其他成员
+func (g *GossipEngine) handoff(peers []string) error {
+	if g.local() == nil {
+		return nil
+	}
+
+	for _, i := range rand.Perm(len(peers)) {
+		if err := g.syncWith(peers[i]); err != nil {
+			fmt.Printf("Failed to hand off data to node %s: %v\n", peers[i], err)
+			continue
+		}
+		return nil
+	}
+	return ErrHandoffFailed
+}
+
+// handleJoin 接受新节点的加入，返回已知的成员列表
+func (g *GossipEngine) handleJoin(c *gin.Context) {
+	var request JoinRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.From.Address == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid join request"})
+		return
+	}
+	if g.members.left() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Node has left the cluster"})
+		return
+	}
+
+	g.receive([]MemberUpdate{request.From})
+	c.JSON(http.StatusOK, JoinResponse{Members: g.members.updates()})
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrGossipDisabled 表示未开启 Gossip，本节点不属于任何集群
+var ErrGossipDisabled = errors.New("gossip is disabled")
+
+// ClusterData 定义集群成员列表的响应数据
+type ClusterData struct {
+	Self    Member   `json:"self"`    // 本节点
+	Members []Member `json:"members"` // 其他成员，包括被怀疑和已死亡的成员
+	Alive   int      `json:"alive"`   // 包括本节点在内的存活成员数量
+}
+
+// JoinClusterRequest 定义加入集群的请求体
+type JoinClusterRequest struct {
+	Seeds []string `json:"seeds" binding:"required"` // 种子节点的 Gossip 地址，任意一个可以访问即可加入
+}
+
+// clusterData 返回当前的成员列表
+func (db *EchoDB) clusterData() ClusterData {
+	self, members := db.Gossip.Members()
+	data := ClusterData{Self: self, Members: members}
+	if self.State == MemberAlive {
+		data.Alive++
+	}
+	for _, member := range members {
+		if member.State == MemberAlive {
+			data.Alive++
+		}
+	}
+	return data
+}
+
+// GetMembers 查询集群成员
+// @Summary 查询集群成员
+// @Description 返回 SWIM 协议维护的成员列表，每个成员带有状态（alive、suspect、dead、left）、incarnation 以及进入当前状态的时间。
+// @Tags cluster
+// @Produce  json
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 409 {object} KVResponse "未开启 Gossip"
+// @Router /cluster/members [get]
+func (db *EchoDB) GetMembers(context *gin.Context) {
+	if db.Gossip == nil {
+		respondError(context, ErrGossipDisabled)
+		return
+	}
+
+	respondSuccess(context, db.clusterData())
+}
+
+// JoinCluster 通过种子节点加入集群
+// @Summary 加入集群
+// @Description 向种子节点发送加入请求并获取成员列表，种子节点会把本节点的加入宣告给集群，之后本节点与新发现的成员同步数据。
+// @Description 无需修改其他节点的 gossip.peers。离开集群的节点也可以通过该接口重新加入。
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param body body JoinClusterRequest true "种子节点地址"
+// @Success 200 {object} KVResponse "加入成功，data 为成员列表"
+// @Failure 400 {object} KVResponse "无效的输入"
+// @Failure 409 {object} KVResponse "未开启 Gossip"
+// @Failure 503 {object} KVResponse "所有种子节点都无法访问"
+// @Router /admin/cluster/join [post]
+func (db *EchoDB) JoinCluster(context *gin.Context) {
+	var request JoinClusterRequest
+	if err := context.ShouldBindJSON(&request); err != nil || len(request.Seeds) == 0 {
+		respondInvalidInput(context)
+		return
+	}
+	if db.Gossip == nil {
+		respondError(context, ErrGossipDisabled)
+		return
+	}
+
+	if _, err := db.Gossip.Join(request.Seeds); err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, db.clusterData())
+}
+
+// LeaveCluster 优雅地离开集群
+// @Summary 离开集群
+// @Description 先把积压的变更发送给其他成员，并与一个成员完成反熵同步交出本节点的数据，然后发送交接期间的写入并向集群宣告离开。
+// @Description 离开后本节点拒绝写入并返回 503，不再探测、复制和同步，可以安全地关闭。
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} KVResponse "已离开集群，data 为成员列表"
+// @Failure 409 {object} KVResponse "未开启 Gossip"
+// @Failure 503 {object} KVResponse "没有成员能够接收本节点的数据"
+// @Router /admin/cluster/leave [post]
+func (db *EchoDB) LeaveCluster(context *gin.Context) {
+	if db.Gossip == nil {
+		respondError(context, ErrGossipDisabled)
+		return
+	}
+
+	if err := db.Gossip.Leave(); err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, db.clusterData())
+}
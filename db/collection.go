@@ -0,0 +1,123 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrFieldNotFound 表示哈希中不存在请求的字段
+var ErrFieldNotFound = errors.New("field not found")
+
+// ErrInvalidCount 表示传入的数量不是正数
+var ErrInvalidCount = errors.New("count must be positive")
+
+// emptyValue 返回指定集合类型的空值
+func emptyValue(kind ValueType) Value {
+	switch kind {
+	case TypeHash:
+		return Value{kind: kind, hash: make(map[string]string)}
+	case TypeSet:
+		return Value{kind: kind, set: make(map[string]struct{})}
+	case TypeZSet:
+		return Value{kind: kind, zset: newSortedSet()}
+	default:
+		return Value{kind: kind}
+	}
+}
+
+// length 返回集合类型的元素数量，标量类型返回 1
+func (v Value) length() int {
+	switch v.kind {
+	case TypeList:
+		return len(v.list)
+	case TypeHash:
+		return len(v.hash)
+	case TypeSet:
+		return len(v.set)
+	case TypeZSet:
+		return v.zset.len()
+	default:
+		return 1
+	}
+}
+
+// normalizeRange 把 [start, stop] 形式的下标换算为 [0, length) 内的闭区间
+// 负数下标从末尾倒数，-1 表示最后一个元素，超出范围的下标按边界截断，区间为空时返回 false
+func normalizeRange(start, stop, length int) (int, int, bool) {
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	stop = min(stop, length-1)
+	return start, stop, start <= stop
+}
+
+// growCollection 为增加元素的写操作取得集合：检查类型，按 growth 估计的内存增量腾出空间，
+// 键不存在时创建一个永不过期的空集合。growth 根据集合当前的内容计算增量
+// 调用方必须持有写锁
+func (db *EchoDB) growCollection(key string, kind ValueType, now time.Time, growth func(value Value) int64) (*Item, error) {
+	current, size := emptyValue(kind), int64(0)
+	item, exists := db.lookup(key, now)
+	if exists {
+		if item.Value.kind != kind {
+			return nil, ErrWrongType
+		}
+		current, size = item.Value, item.size
+	} else {
+		size = estimateSize(key, current)
+	}
+
+	if err := db.ensureCapacity(key, size+growth(current)); err != nil {
+		return nil, err
+	}
+
+	// 腾出空间时可能淘汰了这个键本身，需要重新查找
+	if item, exists = db.data.Get(key); exists {
+		db.touch(key, item, now)
+		return item, nil
+	}
+
+	value := emptyValue(kind)
+	item = &Item{
+		Value:        value,
+		Frequency:    lfuInitValue,
+		LastAccessed: now,
+		size:         estimateSize(key, value),
+	}
+	db.data.Put(key, item)
+	db.usedMemory += item.size
+	db.policy.OnInsert(key, item)
+	return item, nil
+}
+
+// readCollection 取得用于读取或删除元素的集合，键不存在时返回 nil
+// 调用方必须持有写锁
+func (db *EchoDB) readCollection(key string, kind ValueType, now time.Time) (*Item, error) {
+	item, exists := db.lookup(key, now)
+	if !exists {
+		return nil, nil
+	}
+	if item.Value.kind != kind {
+		return nil, ErrWrongType
+	}
+
+	db.touch(key, item, now)
+	return item, nil
+}
+
+// resize 记录集合修改带来的内存变化，与 Redis 一致，修改后为空的集合会被删除
+// changed 表示集合的内容是否确实改变，没有改变时不更新版本号，条件写入和 WATCH 不会因此失败
+// 调用方必须持有写锁
+func (db *EchoDB) resize(key string, item *Item, delta int64, changed bool) {
+	item.size += delta
+	db.usedMemory += delta
+	if item.Value.length() == 0 {
+		db.dropKey(key)
+		return
+	}
+	if changed {
+		db.bumpVersion(item)
+	}
+}
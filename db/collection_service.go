@@ -0,0 +1,536 @@
+package db
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HSetRequest 定义写入哈希字段的请求体
+type HSetRequest struct {
+	Fields map[string]string `json:"fields" binding:"required"`
+}
+
+// PushRequest 定义向列表插入元素的请求体
+type PushRequest struct {
+	Elements []string `json:"elements" binding:"required"`
+}
+
+// MembersRequest 定义向集合添加成员的请求体
+type MembersRequest struct {
+	Members []string `json:"members" binding:"required"`
+}
+
+// CountData 定义只返回数量的结果，例如新增、删除的数量或操作后的长度
+type CountData struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// FieldData 定义哈希单个字段的返回数据
+type FieldData struct {
+	Key   string `json:"key"`
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// HashData 定义哈希所有字段的返回数据
+type HashData struct {
+	Key    string            `json:"key"`
+	Fields map[string]string `json:"fields"`
+}
+
+// ElementsData 定义列表元素的返回数据
+type ElementsData struct {
+	Key      string   `json:"key"`
+	Elements []string `json:"elements"`
+}
+
+// MembersData 定义集合成员的返回数据
+type MembersData struct {
+	Key     string   `json:"key,omitempty"`
+	Members []string `json:"members"`
+}
+
+// respondInvalidInput 返回请求参数无效的响应
+func respondInvalidInput(context *gin.Context) {
+	context.JSON(http.StatusBadRequest, KVResponse{
+		Code:    "400",
+		Message: "Invalid input",
+	})
+}
+
+// respondSuccess 返回成功的响应
+func respondSuccess(context *gin.Context, data interface{}) {
+	context.JSON(http.StatusOK, KVResponse{
+		Code:    "200",
+		Message: "success",
+		Data:    data,
+	})
+}
+
+// HSetFields 写入哈希字段
+// @Summary 写入哈希字段
+// @Description 写入哈希的一个或多个字段，返回新增字段的数量。键不存在时创建永不过期的哈希。
+// @Tags hash
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body HSetRequest true "字段和值"
+// @Success 200 {object} KVResponse "写入成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "键的类型不是哈希"
+// @Router /kv/{key}/hash [post]
+func (db *EchoDB) HSetFields(context *gin.Context) {
+	key := context.Param("key")
+
+	var request HSetRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	added, err := db.HSet(key, request.Fields)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, CountData{Key: key, Count: added})
+}
+
+// HGetField 查询哈希字段
+// @Summary 查询哈希字段
+// @Description 返回哈希中一个字段的值。
+// @Tags hash
+// @Produce  json
+// @Param key path string true "键名"
+// @Param field path string true "字段名"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 404 {object} KVResponse "键或字段不存在"
+// @Failure 409 {object} KVResponse "键的类型不是哈希"
+// @Router /kv/{key}/hash/{field} [get]
+func (db *EchoDB) HGetField(context *gin.Context) {
+	key, field := context.Param("key"), context.Param("field")
+
+	value, err := db.HGet(key, field)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, FieldData{Key: key, Field: field, Value: value})
+}
+
+// HDelField 删除哈希字段
+// @Summary 删除哈希字段
+// @Description 删除哈希中的一个字段，返回实际删除的数量。最后一个字段被删除后键也会被删除。
+// @Tags hash
+// @Produce  json
+// @Param key path string true "键名"
+// @Param field path string true "字段名"
+// @Success 200 {object} KVResponse "删除成功"
+// @Failure 409 {object} KVResponse "键的类型不是哈希"
+// @Router /kv/{key}/hash/{field} [delete]
+func (db *EchoDB) HDelField(context *gin.Context) {
+	key := context.Param("key")
+
+	removed, err := db.HDel(key, context.Param("field"))
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, CountData{Key: key, Count: removed})
+}
+
+// HGetAllFields 查询哈希的所有字段
+// @Summary 查询哈希的所有字段
+// @Description 返回哈希的所有字段和值，键不存在时返回空的哈希。
+// @Tags hash
+// @Produce  json
+// @Param key path string true "键名"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 409 {object} KVResponse "键的类型不是哈希"
+// @Router /kv/{key}/hash [get]
+func (db *EchoDB) HGetAllFields(context *gin.Context) {
+	key := context.Param("key")
+
+	fields, err := db.HGetAll(key)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, HashData{Key: key, Fields: fields})
+}
+
+// LPushElements 向列表头部插入元素
+// @Summary 列表头部插入
+// @Description 把元素依次插入列表头部，返回插入后列表的长度。键不存在时创建永不过期的列表。
+// @Tags list
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body PushRequest true "插入的元素"
+// @Success 200 {object} KVResponse "插入成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "键的类型不是列表"
+// @Router /kv/{key}/list/lpush [post]
+func (db *EchoDB) LPushElements(context *gin.Context) {
+	db.pushElements(context, db.LPush)
+}
+
+// RPushElements 向列表尾部追加元素
+// @Summary 列表尾部追加
+// @Description 把元素依次追加到列表尾部，返回追加后列表的长度。键不存在时创建永不过期的列表。
+// @Tags list
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body PushRequest true "追加的元素"
+// @Success 200 {object} KVResponse "追加成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "键的类型不是列表"
+// @Router /kv/{key}/list/rpush [post]
+func (db *EchoDB) RPushElements(context *gin.Context) {
+	db.pushElements(context, db.RPush)
+}
+
+// pushElements 处理列表的插入请求
+func (db *EchoDB) pushElements(context *gin.Context, push func(string, ...string) (int, error)) {
+	key := context.Param("key")
+
+	var request PushRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	length, err := push(key, request.Elements...)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, CountData{Key: key, Count: length})
+}
+
+// LPopElements 从列表头部弹出元素
+// @Summary 列表头部弹出
+// @Description 从列表头部弹出最多 count 个元素，键不存在时返回空列表。最后一个元素被弹出后键也会被删除。
+// @Tags list
+// @Produce  json
+// @Param key path string true "键名"
+// @Param count query int false "弹出的数量，默认 1"
+// @Success 200 {object} KVResponse "弹出成功"
+// @Failure 400 {object} KVResponse "无效的数量"
+// @Failure 409 {object} KVResponse "键的类型不是列表"
+// @Router /kv/{key}/list/lpop [post]
+func (db *EchoDB) LPopElements(context *gin.Context) {
+	db.popElements(context, db.LPop)
+}
+
+// RPopElements 从列表尾部弹出元素
+// @Summary 列表尾部弹出
+// @Description 从列表尾部弹出最多 count 个元素，键不存在时返回空列表。最后一个元素被弹出后键也会被删除。
+// @Tags list
+// @Produce  json
+// @Param key path string true "键名"
+// @Param count query int false "弹出的数量，默认 1"
+// @Success 200 {object} KVResponse "弹出成功"
+// @Failure 400 {object} KVResponse "无效的数量"
+// @Failure 409 {object} KVResponse "键的类型不是列表"
+// @Router /kv/{key}/list/rpop [post]
+func (db *EchoDB) RPopElements(context *gin.Context) {
+	db.popElements(context, db.RPop)
+}
+
+// popElements 处理列表的弹出请求
+func (db *EchoDB) popElements(context *gin.Context, pop func(string, int) ([]string, error)) {
+	key := context.Param("key")
+
+	count, err := strconv.Atoi(context.DefaultQuery("count", "1"))
+	if err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	elements, err := pop(key, count)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, ElementsData{Key: key, Elements: elements})
+}
+
+// LRangeElements 查询列表的一段元素
+// @Summary 查询列表
+// @Description 返回列表中下标在 [start, stop] 之间的元素，负数下标从列表末尾倒数。默认返回整个列表，键不存在时返回空列表。
+// @Tags list
+// @Produce  json
+// @Param key path string true "键名"
+// @Param start query int false "起始下标（包含），默认 0"
+// @Param stop query int false "结束下标（包含），默认 -1"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 400 {object} KVResponse "无效的下标"
+// @Failure 409 {object} KVResponse "键的类型不是列表"
+// @Router /kv/{key}/list [get]
+func (db *EchoDB) LRangeElements(context *gin.Context) {
+	key := context.Param("key")
+
+	start, startErr := strconv.Atoi(context.DefaultQuery("start", "0"))
+	stop, stopErr := strconv.Atoi(context.DefaultQuery("stop", "-1"))
+	if startErr != nil || stopErr != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	elements, err := db.LRange(key, start, stop)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, ElementsData{Key: key, Elements: elements})
+}
+
+// SAddMembers 向集合添加成员
+// @Summary 添加集合成员
+// @Description 向集合添加一个或多个成员，返回新增成员的数量。键不存在时创建永不过期的集合。
+// @Tags set
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body MembersRequest true "添加的成员"
+// @Success 200 {object} KVResponse "添加成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "键的类型不是集合"
+// @Router /kv/{key}/set [post]
+func (db *EchoDB) SAddMembers(context *gin.Context) {
+	key := context.Param("key")
+
+	var request MembersRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	added, err := db.SAdd(key, request.Members...)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, CountData{Key: key, Count: added})
+}
+
+// SRemMember 从集合移除成员
+// @Summary 移除集合成员
+// @Description 从集合移除一个成员，返回实际移除的数量。最后一个成员被移除后键也会被删除。
+// @Tags set
+// @Produce  json
+// @Param key path string true "键名"
+// @Param member path string true "成员"
+// @Success 200 {object} KVResponse "移除成功"
+// @Failure 409 {object} KVResponse "键的类型不是集合"
+// @Router /kv/{key}/set/{member} [delete]
+func (db *EchoDB) SRemMember(context *gin.Context) {
+	key := context.Param("key")
+
+	removed, err := db.SRem(key, context.Param("member"))
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, CountData{Key: key, Count: removed})
+}
+
+// SMembersList 查询集合的所有成员
+// @Summary 查询集合成员
+// @Description 返回按字典序排列的集合成员，键不存在时返回空集合。
+// @Tags set
+// @Produce  json
+// @Param key path string true "键名"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 409 {object} KVResponse "键的类型不是集合"
+// @Router /kv/{key}/set [get]
+func (db *EchoDB) SMembersList(context *gin.Context) {
+	key := context.Param("key")
+
+	members, err := db.SMembers(key)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, MembersData{Key: key, Members: members})
+}
+
+// SInterMembers 查询多个集合的交集
+// @Summary 集合交集
+// @Description 返回所有指定集合的交集，按字典序排列。任意一个键不存在时交集为空。
+// @Tags set
+// @Produce  json
+// @Param key query []string true "参与求交的键，可以重复指定" collectionFormat(multi)
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 400 {object} KVResponse "未指定键"
+// @Failure 409 {object} KVResponse "存在类型不是集合的键"
+// @Router /sets/inter [get]
+func (db *EchoDB) SInterMembers(context *gin.Context) {
+	members, err := db.SInter(context.QueryArray("key")...)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, MembersData{Members: members})
+}
+
+// ZAddRequest 定义写入有序集合成员的请求体
+type ZAddRequest struct {
+	Members []ZMember `json:"members" binding:"required"`
+}
+
+// ZMembersData 定义有序集合成员的返回数据
+type ZMembersData struct {
+	Key     string    `json:"key"`
+	Members []ZMember `json:"members"`
+}
+
+// RankData 定义有序集合成员排名的返回数据
+type RankData struct {
+	Key    string `json:"key"`
+	Member string `json:"member"`
+	Rank   int    `json:"rank"`
+}
+
+// ZAddMembers 写入有序集合成员
+// @Summary 写入有序集合成员
+// @Description 写入一个或多个成员及其分数，返回新增成员的数量，已有成员的分数会被更新。键不存在时创建永不过期的有序集合。
+// @Tags zset
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body ZAddRequest true "成员和分数"
+// @Success 200 {object} KVResponse "写入成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "键的类型不是有序集合"
+// @Router /kv/{key}/zset [post]
+func (db *EchoDB) ZAddMembers(context *gin.Context) {
+	key := context.Param("key")
+
+	var request ZAddRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	added, err := db.ZAdd(key, request.Members...)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, CountData{Key: key, Count: added})
+}
+
+// ZRemMember 从有序集合移除成员
+// @Summary 移除有序集合成员
+// @Description 从有序集合移除一个成员，返回实际移除的数量。最后一个成员被移除后键也会被删除。
+// @Tags zset
+// @Produce  json
+// @Param key path string true "键名"
+// @Param member path string true "成员"
+// @Success 200 {object} KVResponse "移除成功"
+// @Failure 409 {object} KVResponse "键的类型不是有序集合"
+// @Router /kv/{key}/zset/{member} [delete]
+func (db *EchoDB) ZRemMember(context *gin.Context) {
+	key := context.Param("key")
+
+	removed, err := db.ZRem(key, context.Param("member"))
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, CountData{Key: key, Count: removed})
+}
+
+// ZRangeMembers 按排名查询有序集合
+// @Summary 按排名查询有序集合
+// @Description 返回排名在 [start, stop] 之间的成员，按分数升序排列，负数下标从末尾倒数。默认返回所有成员，键不存在时返回空列表。
+// @Tags zset
+// @Produce  json
+// @Param key path string true "键名"
+// @Param start query int false "起始排名（包含），默认 0"
+// @Param stop query int false "结束排名（包含），默认 -1"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 400 {object} KVResponse "无效的下标"
+// @Failure 409 {object} KVResponse "键的类型不是有序集合"
+// @Router /kv/{key}/zset [get]
+func (db *EchoDB) ZRangeMembers(context *gin.Context) {
+	key := context.Param("key")
+
+	start, startErr := strconv.Atoi(context.DefaultQuery("start", "0"))
+	stop, stopErr := strconv.Atoi(context.DefaultQuery("stop", "-1"))
+	if startErr != nil || stopErr != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	members, err := db.ZRange(key, start, stop)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, ZMembersData{Key: key, Members: members})
+}
+
+// ZRangeByScoreMembers 按分数查询有序集合
+// @Summary 按分数查询有序集合
+// @Description 返回分数在 [min, max] 之间的成员，按分数升序排列。min 和 max 可以是 -inf、inf 或 +inf，默认不限制，键不存在时返回空列表。查询字符串中的 + 会被解码为空格，+inf 需要写成 %2Binf，也可以直接写 inf。
+// @Tags zset
+// @Produce  json
+// @Param key path string true "键名"
+// @Param min query string false "最小分数（包含），默认 -inf"
+// @Param max query string false "最大分数（包含），默认 +inf，正无穷可写作 inf 或 %2Binf"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 400 {object} KVResponse "无效的分数"
+// @Failure 409 {object} KVResponse "键的类型不是有序集合"
+// @Router /kv/{key}/zset/score [get]
+func (db *EchoDB) ZRangeByScoreMembers(context *gin.Context) {
+	key := context.Param("key")
+
+	min, minErr := parseScore(context.DefaultQuery("min", "-inf"))
+	max, maxErr := parseScore(context.DefaultQuery("max", "+inf"))
+	if minErr != nil || maxErr != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	members, err := db.ZRangeByScore(key, min, max)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, ZMembersData{Key: key, Members: members})
+}
+
+// parseScore 解析查询参数中的分数，支持 inf、+inf 和 -inf
+// 未编码的 +inf 在查询字符串中被解码为 " inf"，因此先去掉首尾的空白
+func parseScore(text string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(text), 64)
+}
+
+// ZRankMember 查询有序集合成员的排名
+// @Summary 查询成员排名
+// @Description 返回成员按分数升序的排名，从 0 开始。
+// @Tags zset
+// @Produce  json
+// @Param key path string true "键名"
+// @Param member path string true "成员"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 404 {object} KVResponse "键或成员不存在"
+// @Failure 409 {object} KVResponse "键的类型不是有序集合"
+// @Router /kv/{key}/zset/rank/{member} [get]
+func (db *EchoDB) ZRankMember(context *gin.Context) {
+	key, member := context.Param("key"), context.Param("member")
+
+	rank, err := db.ZRank(key, member)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	respondSuccess(context, RankData{Key: key, Member: member, Rank: rank})
+}
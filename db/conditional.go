@@ -0,0 +1,96 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrKeyExists 表示只允许在键不存在时写入，但键已经存在
+var ErrKeyExists = errors.New("key already exists")
+
+// ErrVersionMismatch 表示键的当前版本与期望的版本不符，或者键已不存在
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// writeCondition 检查键当前的状态是否允许写入，item 在键不存在时为 nil
+type writeCondition func(item *Item) error
+
+// InsertNX 仅在键不存在时写入，ttl 为 0 表示永不过期，返回写入后的版本号
+// 键已存在时返回 ErrKeyExists
+func (db *EchoDB) InsertNX(key string, value Value, ttl time.Duration) (uint64, error) {
+	return db.insertIf(key, value, ttl, func(item *Item) error {
+		if item != nil {
+			return ErrKeyExists
+		}
+		return nil
+	})
+}
+
+// InsertXX 仅在键已存在时更新，ttl 为 0 表示永不过期，返回写入后的版本号
+// 键不存在时返回 ErrKeyNotFound
+func (db *EchoDB) InsertXX(key string, value Value, ttl time.Duration) (uint64, error) {
+	return db.insertIf(key, value, ttl, func(item *Item) error {
+		if item == nil {
+			return ErrKeyNotFound
+		}
+		return nil
+	})
+}
+
+// CompareAndSwap 仅在键的当前版本等于 expectedVersion 时更新，ttl 为 0 表示永不过期，返回写入后的版本号
+// 键不存在或版本不符时返回 ErrVersionMismatch
+func (db *EchoDB) CompareAndSwap(key string, expectedVersion uint64, value Value, ttl time.Duration) (uint64, error) {
+	return db.insertIf(key, value, ttl, func(item *Item) error {
+		if item == nil || item.Version != expectedVersion {
+			return ErrVersionMismatch
+		}
+		return nil
+	})
+}
+
+// insertIf 在满足条件时写入数据并返回写入后的版本号，condition 为 nil 时无条件写入
+func (db *EchoDB) insertIf(key string, value Value, ttl time.Duration, condition writeCondition) (uint64, error) {
+	if ttl < 0 {
+		return 0, ErrInvalidTTL
+	}
+	if err := value.validate(); err != nil {
+		return 0, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if condition != nil {
+		item, _ := db.lookup(key, now)
+		if err := condition(item); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := db.store(key, value, expirationFor(now, ttl), now); err != nil {
+		return 0, err
+	}
+	return db.version, nil
+}
+
+// bumpVersion 为修改过的数据项分配新的版本号，调用方必须持有写锁
+// 所有键共享同一个递增序列，删除后重新创建的键也不会复用旧的版本号
+func (db *EchoDB) bumpVersion(item *Item) {
+	db.version++
+	item.Version = db.version
+}
+
+// restoreVersion 恢复持久化文件中记录的版本号，保证之后分配的版本号大于已有的版本号
+// 调用方必须持有写锁
+func (db *EchoDB) restoreVersion(key string, version uint64) {
+	if version == 0 {
+		return
+	}
+	db.version = max(db.version, version)
+	if item, exists := db.data.Get(key); exists {
+		item.Version = version
+	}
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ErrNotInteger 表示值不是整数，无法执行整数自增
+var ErrNotInteger = errors.New("value is not an integer or out of range")
+
+// ErrNotFloat 表示值不是数字，无法执行浮点数自增
+var ErrNotFloat = errors.New("value is not a valid float")
+
+// ErrOverflow 表示整数自增的结果超出了 int64 的范围
+var ErrOverflow = errors.New("increment or decrement would overflow")
+
+// integer 把值解释为整数，保存整数文本的字符串和字节值也可以参与计算
+func (v Value) integer() (int64, error) {
+	var text string
+	switch v.kind {
+	case TypeInt:
+		return v.num, nil
+	case TypeFloat:
+		return 0, ErrNotInteger
+	case TypeString:
+		text = v.str
+	case TypeBytes:
+		text = string(v.bytes)
+	default:
+		return 0, ErrWrongType
+	}
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, ErrNotInteger
+	}
+	return n, nil
+}
+
+// number 把值解释为浮点数，整数值会被转换为浮点数
+func (v Value) number() (float64, error) {
+	var text string
+	switch v.kind {
+	case TypeInt:
+		return float64(v.num), nil
+	case TypeFloat:
+		return v.float, nil
+	case TypeString:
+		text = v.str
+	case TypeBytes:
+		text = string(v.bytes)
+	default:
+		return 0, ErrWrongType
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, ErrNotFloat
+	}
+	return f, nil
+}
+
+// Incr 把键的整数值加一，返回加一后的值
+func (db *EchoDB) Incr(key string) (int64, error) {
+	return db.IncrBy(key, 1)
+}
+
+// Decr 把键的整数值减一，返回减一后的值
+func (db *EchoDB) Decr(key string) (int64, error) {
+	return db.IncrBy(key, -1)
+}
+
+// IncrBy 把键的整数值加上 delta，返回相加后的值
+// 键不存在时视为 0 并创建一个永不过期的键；已有键保留原来的过期时间
+func (db *EchoDB) IncrBy(key string, delta int64) (int64, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	return db.incrBy(key, delta, time.Now())
+}
+
+// IncrByFloat 把键的数值加上 delta，返回相加后的值，结果总是保存为浮点数
+// 键不存在时视为 0 并创建一个永不过期的键；已有键保留原来的过期时间
+func (db *EchoDB) IncrByFloat(key string, delta float64) (float64, error) {
+	if err := checkIncrement(delta); err != nil {
+		return 0, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	return db.incrByFloat(key, delta, time.Now())
+}
+
+// checkIncrement 检查浮点数增量是否是有限的数
+func checkIncrement(delta float64) error {
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return fmt.Errorf("%w: increment must be finite", ErrInvalidValue)
+	}
+	return nil
+}
+
+// incrBy 是 IncrBy 的实现，结果会记录到 AOF，调用方必须持有写锁
+func (db *EchoDB) incrBy(key string, delta int64, now time.Time) (int64, error) {
+	current, expiration := int64(0), time.Time{}
+	if item, exists := db.lookup(key, now); exists {
+		n, err := item.Value.integer()
+		if err != nil {
+			return 0, err
+		}
+		current, expiration = n, item.Expiration
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	result := current + delta
+	if err := db.store(key, IntValue(result), expiration, now); err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+// incrByFloat 是 IncrByFloat 的实现，结果会记录到 AOF，调用方必须持有写锁
+func (db *EchoDB) incrByFloat(key string, delta float64, now time.Time) (float64, error) {
+	current, expiration := 0.0, time.Time{}
+	if item, exists := db.lookup(key, now); exists {
+		f, err := item.Value.number()
+		if err != nil {
+			return 0, err
+		}
+		current, expiration = f, item.Expiration
+	}
+
+	result := current + delta
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("%w: increment would produce NaN or Infinity", ErrNotFloat)
+	}
+
+	if err := db.store(key, FloatValue(result), expiration, now); err != nil {
+		return 0, err
+	}
+	return result, nil
+}
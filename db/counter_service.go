@@ -0,0 +1,118 @@
+package db
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"math"
+)
+
+// IncrRequest 定义整数自增的请求体，请求体可以省略
+type IncrRequest struct {
+	Delta *int64 `json:"delta"` // 增量，省略时为 1
+}
+
+// IncrByFloatRequest 定义浮点数自增的请求体
+type IncrByFloatRequest struct {
+	Delta *float64 `json:"delta" binding:"required"` // 增量，可以为负数
+}
+
+// CounterData 定义自增后的返回数据
+type CounterData struct {
+	Key   string `json:"key"`
+	Value Value  `json:"value"`
+}
+
+// IncrKey 整数自增
+// @Summary 整数自增
+// @Description 把键的整数值加上 delta 并返回结果，delta 省略时为 1。键不存在时从 0 开始计算并创建永不过期的键，已有键保留原来的过期时间。
+// @Tags counter
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body IncrRequest false "增量"
+// @Success 200 {object} KVResponse "自增成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "值不是整数或结果溢出"
+// @Router /kv/{key}/incr [post]
+func (db *EchoDB) IncrKey(context *gin.Context) {
+	db.incrKey(context, 1)
+}
+
+// DecrKey 整数自减
+// @Summary 整数自减
+// @Description 把键的整数值减去 delta 并返回结果，delta 省略时为 1。键不存在时从 0 开始计算并创建永不过期的键，已有键保留原来的过期时间。
+// @Tags counter
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body IncrRequest false "减量"
+// @Success 200 {object} KVResponse "自减成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "值不是整数或结果溢出"
+// @Router /kv/{key}/decr [post]
+func (db *EchoDB) DecrKey(context *gin.Context) {
+	db.incrKey(context, -1)
+}
+
+// incrKey 处理整数自增和自减，sign 为 -1 时表示自减
+func (db *EchoDB) incrKey(context *gin.Context, sign int64) {
+	key := context.Param("key")
+
+	var request IncrRequest
+	if err := context.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		respondInvalidInput(context)
+		return
+	}
+
+	delta := int64(1)
+	if request.Delta != nil {
+		delta = *request.Delta
+	}
+
+	var result int64
+	var err error
+	if sign > 0 {
+		result, err = db.IncrBy(key, delta)
+	} else if delta == math.MinInt64 {
+		err = ErrOverflow
+	} else {
+		result, err = db.IncrBy(key, -delta)
+	}
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	respondSuccess(context, CounterData{Key: key, Value: IntValue(result)})
+}
+
+// IncrByFloatKey 浮点数自增
+// @Summary 浮点数自增
+// @Description 把键的数值加上 delta 并以浮点数保存，返回相加后的结果。键不存在时从 0 开始计算并创建永不过期的键，已有键保留原来的过期时间。
+// @Tags counter
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body IncrByFloatRequest true "增量"
+// @Success 200 {object} KVResponse "自增成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 409 {object} KVResponse "值不是数字或结果不是有限数"
+// @Router /kv/{key}/incrbyfloat [post]
+func (db *EchoDB) IncrByFloatKey(context *gin.Context) {
+	key := context.Param("key")
+
+	var request IncrByFloatRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	result, err := db.IncrByFloat(key, *request.Delta)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	respondSuccess(context, CounterData{Key: key, Value: FloatValue(result)})
+}
@@ -2,208 +2,436 @@ package db
 
 import (
 	"echoDB/config"
-	"fmt"
+	"errors"
 	"sync"
 	"time"
 )
 
+// ErrKeyNotFound 表示请求的键不存在
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrInvalidTTL 表示传入的过期时间不合法
+var ErrInvalidTTL = errors.New("invalid expire time")
+
+// NoExpiration 是 TTL 对永不过期的键返回的剩余时间
+const NoExpiration time.Duration = -1
+
 // Item 表示数据库中的一项数据
 type Item struct {
-	Value        interface{} // 存储的值
-	Frequency    int         // 访问频率
-	LastAccessed time.Time   // 最后访问时间
-	Expiration   time.Time   // 过期时间
+	Value        Value         `json:"value"`              // 带类型标签的值
+	Frequency    int           `json:"frequency"`          // 对数访问计数器，随空闲时间衰减
+	LastAccessed time.Time     `json:"last_accessed"`      // 最后访问时间
+	Expiration   time.Time     `json:"expiration"`         // 过期时间
+	Version      uint64        `json:"version"`            // 版本号，每次修改都会增大
+	Stamp        Stamp         `json:"stamp"`              // 最近一次写入的 HLC 时间戳，并发写入时据此决定胜者
+	Clock        VersionVector `json:"clock,omitempty"`    // 版本向量，记录各节点对该键的写入，开启复制时维护
+	Siblings     []Sibling     `json:"siblings,omitempty"` // 按 siblings 解决冲突时保留的并发写入，客户端重新写入该键即解决冲突
+	size         int64         // 估算的内存占用，单位为字节
+	digest       uint64        // 该键在反熵同步摘要中的哈希，时间戳、版本向量或兄弟值变化时重新计算，0 表示尚未计算
+}
+
+// expired 判断数据项在 now 时刻是否已过期，零值 Expiration 表示永不过期
+func (item *Item) expired(now time.Time) bool {
+	return !item.Expiration.IsZero() && !now.Before(item.Expiration)
+}
+
+// clone 返回数据项的副本，其中的值被深拷贝，可以在锁外安全读取
+func (item *Item) clone() *Item {
+	result := *item
+	result.Value = item.Value.Clone()
+	return &result
 }
 
+// expirationFor 根据 ttl 计算过期时间，ttl 为 0 时返回零值表示永不过期
+func expirationFor(now time.Time, ttl time.Duration) time.Time {
+	if ttl == 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// Entry 表示带键名的数据项，用于返回查询结果
+type Entry struct {
+	Key string `json:"key"`
+	Item
+}
+
+// indexDegree 是主索引B+树每个节点最多容纳的键数量
+const indexDegree = 32
+
 // EchoDB 是分布式内存数据库的结构
 type EchoDB struct {
-	data      map[string]*Item // 存储数据
-	mutex     sync.RWMutex     // 保护并发访问
-	config    *config.Config
-	Gossip    *GossipEngine
-	bplusTree *BPlusTree    // B+树用于索引
-	maxSize   int           // 最大存储条数
-	lifetime  time.Duration // 数据过期时间
+	data     *BPlusTree[string, *Item] // B+树存储数据，同时作为点查和范围查询的索引
+	mutex    sync.RWMutex              // 保护并发访问
+	config   *config.Config
+	Gossip   *GossipEngine
+	expires  *keySet        // 设置了过期时间的键，供定期删除随机抽样
+	policy   EvictionPolicy // 内存淘汰策略
+	aof      *aofWriter     // 追加写日志，未开启持久化时为 nil
+	txLog    *[]aofRecord   // 事务执行期间收集的 AOF 记录，不在事务中时为 nil
+	lifetime time.Duration  // 数据过期时间
+	version  uint64         // 最近分配的版本号
+
+	nodeID     string                       // 本节点的 ID，时间戳和版本向量中用于区分不同节点的写入
+	clock      hybridClock                  // 为本地写入生成时间戳的混合逻辑时钟
+	resolution Resolution                   // 并发写入的解决方式
+	tombstones map[string]tombstone         // 被删除的键的删除标记，只在开启复制时记录
+	evicted    *BPlusTree[string, eviction] // 被本节点淘汰的键被淘汰时的版本，按键排序以便计算区间摘要，只在开启复制时记录
+
+	maxMemory   int64  // 内存上限，单位为字节，不为正数时不限制
+	maxKeys     int    // 最大存储条数，不为正数时不限制
+	usedMemory  int64  // 当前估算的内存占用
+	evictedKeys uint64 // 累计被淘汰的键数量
+	expiredKeys uint64 // 累计因过期被删除的键数量
+
+	lfuLogFactor int           // LFU 对数计数器的增长因子
+	lfuDecayTime time.Duration // LFU 计数器衰减一次的周期，不为正数时不衰减
+
+	snapshotMutex  sync.Mutex     // 保护快照状态
+	snapshotStatus SnapshotStatus // 快照保存的状态
+
+	aofMutex         sync.Mutex // 保护 AOF 重写的结果
+	lastRewriteTime  time.Time  // 最近一次 AOF 重写完成的时间
+	lastRewriteError string     // 最近一次 AOF 重写失败的原因
 }
 
 // NewEchoDB 创建一个新的EchoDB实例
-func NewEchoDB(config *config.Config) *EchoDB {
+func NewEchoDB(config *config.Config) (*EchoDB, error) {
 	db := &EchoDB{
-		data:      make(map[string]*Item),
-		config:    config,
-		maxSize:   1000,             // 设定最大条目数量
-		lifetime:  10 * time.Minute, // 数据过期时间设为10分钟
-		bplusTree: NewBPlusTree(3),  // 初始化B+树，假设度为3
+		data:     NewBPlusTree[string, *Item](indexDegree),
+		config:   config,
+		lifetime: 10 * time.Minute, // 数据过期时间设为10分钟
+		expires:  newKeySet(),
+
+		maxMemory: config.Eviction.MaxMemory,
+		maxKeys:   config.Eviction.MaxKeys,
+
+		lfuLogFactor: max(config.Eviction.LFULogFactor, 0),
+		lfuDecayTime: time.Duration(config.Eviction.LFUDecayTime) * time.Minute,
+	}
+
+	// 根据配置选择淘汰策略
+	policy, err := NewEvictionPolicy(config.Eviction.Policy, db.maxKeys, db.lfuDecayTime)
+	if err != nil {
+		return nil, err
 	}
+	db.policy = policy
 
-	// 根据配置选择一致性算法
+	// 根据配置选择一致性算法，复制引擎在恢复数据之前创建，以便恢复删除标记
 	if config.ConsistencyAlgorithm == "Gossip" {
-		db.Gossip = NewGossipEngine(config.Gossip.Peers, config.Gossip.Port, config.Gossip.NodeID)
+		resolution, err := parseResolution(config.Gossip.Resolution)
+		if err != nil {
+			return nil, err
+		}
+		db.resolution = resolution
+		db.nodeID = config.Gossip.NodeID
+		db.tombstones = make(map[string]tombstone)
+		db.evicted = NewBPlusTree[string, eviction](indexDegree)
+		db.Gossip = NewGossipEngine(config.Gossip.Peers, config.Gossip.Port, config.Gossip.NodeID, config.Gossip.Address)
+		db.Gossip.attach(db)
+		go db.startTombstoneProcess(time.Duration(config.Gossip.TombstoneTTL) * time.Second)
+	}
+
+	// 开启 AOF 时以日志为准恢复数据，否则从最新的有效快照恢复
+	if config.AOF.Enabled {
+		if err := db.loadAOF(); err != nil {
+			return nil, err
+		}
+		go db.startAOFRewriteProcess()
+	} else if err := db.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	// 启动定时快照任务
+	if config.Snapshot.Interval > 0 {
+		go db.startSnapshotProcess(time.Duration(config.Snapshot.Interval) * time.Second)
 	}
 
 	// 启动定时淘汰任务
 	go db.startEvictionProcess()
 
-	return db
+	return db, nil
 }
 
-// Insert 插入或更新数据
-func (db *EchoDB) Insert(key string, value interface{}) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+// Insert 插入或更新数据，使用默认的过期时间
+func (db *EchoDB) Insert(key string, value Value) error {
+	return db.InsertWithTTL(key, value, db.lifetime)
+}
+
+// InsertWithTTL 插入或更新数据并指定过期时间，ttl 为 0 表示永不过期
+// 与 Redis 的 SET 一致，更新已有键时会用新的 ttl 覆盖原有过期时间
+func (db *EchoDB) InsertWithTTL(key string, value Value, ttl time.Duration) error {
+	_, err := db.insertIf(key, value, ttl, nil)
+	return err
+}
+
+// store 写入数据并记录到 AOF，调用方必须持有写锁
+func (db *EchoDB) store(key string, value Value, expiration time.Time, now time.Time) error {
+	if err := db.set(key, value, expiration, now); err != nil {
+		return err
+	}
+	return db.appendLog(aofRecord{Op: aofSet, Key: key, Value: &value, ExpireAt: encodeExpiration(expiration)})
+}
+
+// set 写入数据并设置过期时间，零值 expiration 表示永不过期，调用方必须持有写锁
+// 存储的是值的副本：集合类型的命令会原地修改存储的值，不能与调用方或其他键共享底层数据
+func (db *EchoDB) set(key string, value Value, expiration time.Time, now time.Time) error {
+	// 已过期的条目先惰性删除，之后按新数据处理
+	db.lookup(key, now)
+	value = value.Clone()
+
+	// 写入前先按淘汰策略腾出空间，覆盖已有的键时保留它的版本向量和兄弟值
+	size := estimateSize(key, value)
+	if item, exists := db.data.Get(key); exists {
+		size += replicaSize(item.Clock, item.Siblings)
+	}
+	if err := db.ensureCapacity(key, size); err != nil {
+		return err
+	}
 
 	// 检查是否需要更新已有的条目
-	if item, exists := db.data[key]; exists {
+	item, exists := db.data.Get(key)
+	if exists {
 		// 更新数据项的值
 		item.Value = value
+		db.usedMemory += size - item.size
+		item.size = size
 		// 更新访问频率和最后访问时间
-		item.Frequency++
-		item.LastAccessed = time.Now()
+		db.touch(key, item, now)
 	} else {
-		// 新数据项，设定过期时间
-		db.data[key] = &Item{
+		// 新数据项
+		item = &Item{
 			Value:        value,
-			Frequency:    1,
-			LastAccessed: time.Now(),
-			Expiration:   time.Now().Add(db.lifetime), // 设置过期时间
+			Frequency:    lfuInitValue,
+			LastAccessed: now,
+			size:         size,
 		}
+		db.data.Put(key, item)
+		db.usedMemory += size
+		db.policy.OnInsert(key, item)
 	}
 
-	// 更新B+树索引
-	db.bplusTree.Insert(key)
-
-	// 如果数据量超出最大存储，进行清理
-	if len(db.data) > db.maxSize {
-		db.evictData()
-	}
+	// 设置过期时间
+	db.setExpiration(key, item, expiration)
+	db.bumpVersion(item)
 
 	return nil
 }
 
-// Delete 删除数据
+// Delete 删除数据，键不存在时返回 ErrKeyNotFound
 func (db *EchoDB) Delete(key string) error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
-	// 删除数据
-	delete(db.data, key)
+	if err := db.writable(); err != nil {
+		return err
+	}
+	return db.del(key, time.Now())
+}
 
-	// 删除B+树索引
-	db.bplusTree.Delete(key)
+// del 删除数据并记录到 AOF，调用方必须持有写锁
+func (db *EchoDB) del(key string, now time.Time) error {
+	if _, exists := db.lookup(key, now); !exists {
+		return ErrKeyNotFound
+	}
 
-	return nil
+	// 删除数据及其索引
+	db.dropKey(key)
+
+	return db.appendLog(aofRecord{Op: aofDel, Key: key})
+}
+
+// Expire 为已有键设置新的过期时间，ttl 不为正数时立即删除该键
+func (db *EchoDB) Expire(key string, ttl time.Duration) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return err
+	}
+	return db.expire(key, ttl, time.Now())
+}
+
+// expire 设置过期时间并记录到 AOF，调用方必须持有写锁
+func (db *EchoDB) expire(key string, ttl time.Duration, now time.Time) error {
+	item, exists := db.lookup(key, now)
+	if !exists {
+		return ErrKeyNotFound
+	}
+
+	// 与 Redis 一致，非正数的过期时间等同于删除
+	if ttl <= 0 {
+		db.dropKey(key)
+		return db.appendLog(aofRecord{Op: aofDel, Key: key})
+	}
+
+	expiration := now.Add(ttl)
+	db.setExpiration(key, item, expiration)
+	db.bumpVersion(item)
+	return db.appendLog(aofRecord{Op: aofExpire, Key: key, ExpireAt: encodeExpiration(expiration)})
+}
+
+// Persist 移除键的过期时间，使其永不过期
+func (db *EchoDB) Persist(key string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return err
+	}
+	return db.persist(key, time.Now())
+}
+
+// persist 移除过期时间并记录到 AOF，调用方必须持有写锁
+func (db *EchoDB) persist(key string, now time.Time) error {
+	item, exists := db.lookup(key, now)
+	if !exists {
+		return ErrKeyNotFound
+	}
+
+	db.setExpiration(key, item, time.Time{})
+	db.bumpVersion(item)
+	return db.appendLog(aofRecord{Op: aofPersist, Key: key})
 }
 
-// Query 查询数据
-func (db *EchoDB) Query(key string) (interface{}, bool) {
+// TTL 返回键的剩余存活时间，永不过期的键返回 NoExpiration
+func (db *EchoDB) TTL(key string) (time.Duration, error) {
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
 
-	item, exists := db.data[key]
-	if exists {
-		// 更新访问频率和最后访问时间
-		item.Frequency++
-		item.LastAccessed = time.Now()
+	// 读锁下无法删除，已过期的键仅按不存在处理
+	item, exists := db.data.Get(key)
+	if !exists || item.expired(time.Now()) {
+		return 0, ErrKeyNotFound
 	}
 
-	return item, exists
+	if item.Expiration.IsZero() {
+		return NoExpiration, nil
+	}
+
+	remaining := time.Until(item.Expiration)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, nil
 }
 
-// evictData 根据LFU + LRU 策略删除数据
-func (db *EchoDB) evictData() {
-	var lowestFreqItems []*Item
-	var lowestFreq int
+// Query 查询数据，返回数据项的副本
+func (db *EchoDB) Query(key string) (*Item, bool) {
+	// 查询会更新访问统计，因此需要写锁
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
 
-	// 找到最低访问频率
-	for _, item := range db.data {
-		if lowestFreq == 0 || item.Frequency < lowestFreq {
-			lowestFreq = item.Frequency
-			lowestFreqItems = []*Item{item}
-		} else if item.Frequency == lowestFreq {
-			lowestFreqItems = append(lowestFreqItems, item)
-		}
+	now := time.Now()
+	item, exists := db.lookup(key, now)
+	if !exists {
+		return nil, false
 	}
 
-	// 如果有多个条目访问频率相同，使用LRU策略（最久未访问的条目）
-	if len(lowestFreqItems) > 1 {
-		// 找到最久未访问的条目
-		var oldestItem *Item
-		for _, item := range lowestFreqItems {
-			if oldestItem == nil || item.LastAccessed.Before(oldestItem.LastAccessed) {
-				oldestItem = item
-			}
-		}
-		// 删除最久未访问的条目
-		for key, item := range db.data {
-			if item == oldestItem {
-				delete(db.data, key)
-				db.bplusTree.Delete(key)
-				break
-			}
-		}
+	// 更新访问频率和最后访问时间
+	db.touch(key, item, now)
+
+	return item.clone(), true
+}
+
+// Type 返回键对应的值的类型
+func (db *EchoDB) Type(key string) (ValueType, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	item, exists := db.data.Get(key)
+	if !exists || item.expired(time.Now()) {
+		return "", ErrKeyNotFound
+	}
+	return item.Value.Type(), nil
+}
+
+// lookup 查找键对应的数据项，已过期的数据项会被惰性删除并按不存在处理
+// 调用方必须持有写锁
+func (db *EchoDB) lookup(key string, now time.Time) (*Item, bool) {
+	item, exists := db.data.Get(key)
+	if !exists {
+		return nil, false
+	}
+
+	if item.expired(now) {
+		db.expireKey(key)
+		return nil, false
+	}
+
+	return item, true
+}
+
+// touch 记录一次访问：先按空闲时间衰减访问计数，再以对数概率递增，并通知淘汰策略
+// 调用方必须持有写锁
+func (db *EchoDB) touch(key string, item *Item, now time.Time) {
+	counter := lfuDecay(item.Frequency, item.LastAccessed, now, db.lfuDecayTime)
+	item.Frequency = lfuLogIncr(counter, db.lfuLogFactor)
+	item.LastAccessed = now
+	db.policy.OnAccess(key, item)
+}
+
+// setExpiration 设置数据项的过期时间并维护过期键集合，调用方必须持有写锁
+func (db *EchoDB) setExpiration(key string, item *Item, expiration time.Time) {
+	item.Expiration = expiration
+	if expiration.IsZero() {
+		db.expires.Remove(key)
 	} else {
-		// 只有一个最低频率的数据，删除它
-		for key, item := range db.data {
-			if item == lowestFreqItems[0] {
-				delete(db.data, key)
-				db.bplusTree.Delete(key)
-				break
-			}
-		}
+		db.expires.Add(key)
 	}
 }
 
-// evictExpiredData 清理过期数据
-func (db *EchoDB) evictExpiredData() {
-	currentTime := time.Now()
+// expireKey 删除一个已过期的键，调用方必须持有写锁
+func (db *EchoDB) expireKey(key string) {
+	db.removeKey(key)
+	db.expiredKeys++
+	db.propagateDelete(key)
+}
 
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+// removeKey 删除数据及其过期信息，调用方必须持有写锁
+func (db *EchoDB) removeKey(key string) {
+	if item, exists := db.data.Delete(key); exists {
+		db.usedMemory -= item.size
+	}
+	db.expires.Remove(key)
+	db.policy.OnDelete(key)
+}
 
-	// 遍历所有数据，删除已过期的
-	for key, item := range db.data {
-		if item.Expiration.Before(currentTime) {
-			delete(db.data, key)
-			db.bplusTree.Delete(key)
-		}
+// evictData 按配置的淘汰策略删除一个键，没有可淘汰的键时返回 false
+// 调用方必须持有写锁
+func (db *EchoDB) evictData() bool {
+	key, ok := db.policy.Victim()
+	if !ok {
+		return false
 	}
+	db.recordEviction(key, time.Now())
+	db.removeKey(key)
+	db.evictedKeys++
+	db.propagateDelete(key)
+	return true
 }
 
 // startEvictionProcess 定期检查并淘汰数据
 func (db *EchoDB) startEvictionProcess() {
-	ticker := time.NewTicker(1 * time.Minute) // 每分钟检查一次
+	ticker := time.NewTicker(expireCycleInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		// 清理过期数据
-		db.evictExpiredData()
+		// 随机抽样清理过期数据
+		db.activeExpireCycle()
 
-		// 如果数据量超出最大存储，进行清理
-		if len(db.data) > db.maxSize {
-			db.evictData()
-		}
-	}
-}
-
-// RangeQuery 支持范围查询，利用B+树来实现
-func (db *EchoDB) RangeQuery(startKey, endKey string) {
-	node := db.bplusTree.root
-	var result []string
-
-	// 使用 B+树的叶子节点遍历来支持范围查询
-	for node != nil {
-		for _, key := range node.keys {
-			if key >= startKey && key <= endKey {
-				result = append(result, key)
+		// 如果数据量或内存占用超出上限，进行清理
+		db.mutex.Lock()
+		for !db.withinLimits(0, 0) {
+			if !db.evictData() {
+				break
 			}
 		}
-		node = node.next
+		db.mutex.Unlock()
 	}
-
-	fmt.Println("Range query result:", result)
 }
 
 // PrintIndex 打印B+树的索引结构
 func (db *EchoDB) PrintIndex() {
-	db.bplusTree.PrintTree(db.bplusTree.root, 0)
+	db.data.PrintTree(db.data.root, 0)
 }
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOutOfMemory 表示已达到容量上限且淘汰策略无法腾出空间
+var ErrOutOfMemory = errors.New("OOM command not allowed when capacity is exhausted")
+
+// 支持的淘汰策略名称，对应配置文件中的 eviction.policy
+const (
+	PolicyLRU        = "lru"
+	PolicyLFU        = "lfu"
+	PolicyLFUAging   = "lfu-aging"
+	PolicyWTinyLFU   = "w-tinylfu"
+	PolicyARC        = "arc"
+	PolicyRandom     = "random"
+	PolicyNoEviction = "noeviction"
+)
+
+// EvictionPolicy 是内存淘汰策略
+// 所有方法都在 EchoDB 持有写锁时调用，实现无需自行加锁
+type EvictionPolicy interface {
+	// OnInsert 在写入新键后调用
+	OnInsert(key string, item *Item)
+	// OnAccess 在读取或覆盖已有键后调用
+	OnAccess(key string, item *Item)
+	// OnDelete 在键被删除、过期或淘汰后调用
+	OnDelete(key string)
+	// Victim 选出下一个应当淘汰的键，没有可淘汰的键时返回 false
+	Victim() (string, bool)
+}
+
+// NewEvictionPolicy 根据名称创建淘汰策略，名称为空时使用 LFU
+// capacity 是预期容纳的键数量，部分策略用它确定内部结构的大小；
+// lfuDecayTime 是 LFU 计数器衰减一次的周期，不为正数时不衰减
+func NewEvictionPolicy(name string, capacity int, lfuDecayTime time.Duration) (EvictionPolicy, error) {
+	switch name {
+	case PolicyLRU:
+		return newLRUPolicy(), nil
+	case PolicyLFU, "":
+		return newLFUCache(lfuDecayTime), nil
+	case PolicyLFUAging:
+		return newLFUAgingPolicy(), nil
+	case PolicyWTinyLFU:
+		return newTinyLFUPolicy(capacity), nil
+	case PolicyARC:
+		return newARCPolicy(), nil
+	case PolicyRandom:
+		return newRandomPolicy(), nil
+	case PolicyNoEviction:
+		return noEvictionPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy %q", name)
+	}
+}
+
+// lruPolicy 淘汰最久未访问的键
+type lruPolicy struct {
+	order   *list.List // 从前到后按最近访问到最久未访问排列
+	entries map[string]*list.Element
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (p *lruPolicy) OnInsert(key string, item *Item) {
+	if element, exists := p.entries[key]; exists {
+		p.order.MoveToFront(element)
+		return
+	}
+	p.entries[key] = p.order.PushFront(key)
+}
+
+func (p *lruPolicy) OnAccess(key string, item *Item) {
+	if element, exists := p.entries[key]; exists {
+		p.order.MoveToFront(element)
+	}
+}
+
+func (p *lruPolicy) OnDelete(key string) {
+	if element, exists := p.entries[key]; exists {
+		p.order.Remove(element)
+		delete(p.entries, key)
+	}
+}
+
+func (p *lruPolicy) Victim() (string, bool) {
+	if back := p.order.Back(); back != nil {
+		return back.Value.(string), true
+	}
+	return "", false
+}
+
+// randomPolicy 随机淘汰一个键
+type randomPolicy struct {
+	keys *keySet
+}
+
+func newRandomPolicy() *randomPolicy {
+	return &randomPolicy{keys: newKeySet()}
+}
+
+func (p *randomPolicy) OnInsert(key string, item *Item) { p.keys.Add(key) }
+
+func (p *randomPolicy) OnAccess(key string, item *Item) {}
+
+func (p *randomPolicy) OnDelete(key string) { p.keys.Remove(key) }
+
+func (p *randomPolicy) Victim() (string, bool) { return p.keys.Random() }
+
+// noEvictionPolicy 从不淘汰数据，容量耗尽后拒绝写入新键
+type noEvictionPolicy struct{}
+
+func (noEvictionPolicy) OnInsert(key string, item *Item) {}
+
+func (noEvictionPolicy) OnAccess(key string, item *Item) {}
+
+func (noEvictionPolicy) OnDelete(key string) {}
+
+func (noEvictionPolicy) Victim() (string, bool) { return "", false }
@@ -0,0 +1,58 @@
+package db
+
+import "time"
+
+// 定期删除的参数，取值参考 Redis 的 activeExpireCycle
+const (
+	expireCycleInterval    = 100 * time.Millisecond // 两次定期删除之间的间隔
+	expireCycleTimeLimit   = 25 * time.Millisecond  // 单次定期删除最多占用的时间
+	expireKeysPerLoop      = 20                     // 每轮随机抽样的键数量
+	expireAcceptableStale  = 25                     // 抽样中过期键占比超过该百分比时继续下一轮
+	expireChecksPerTimeout = 16                     // 每执行多少轮检查一次是否超时
+)
+
+// activeExpireCycle 以随机抽样的方式定期删除过期数据
+// 每轮只在写锁内抽样少量设置了过期时间的键，若抽样中过期键比例较高则继续下一轮，
+// 直到比例降到阈值以下或超过时间限制，避免每次都在写锁内扫描整个键空间
+func (db *EchoDB) activeExpireCycle() {
+	start := time.Now()
+
+	for iteration := 1; ; iteration++ {
+		sampled, expired := db.expireSample(expireKeysPerLoop)
+
+		// 抽样中过期键的比例已经足够低，本次清理结束
+		if sampled == 0 || expired*100 <= sampled*expireAcceptableStale {
+			return
+		}
+
+		// 每隔若干轮检查一次耗时，超时后留给下一个周期继续
+		if iteration%expireChecksPerTimeout == 0 && time.Since(start) > expireCycleTimeLimit {
+			return
+		}
+	}
+}
+
+// expireSample 随机抽样最多 count 个设置了过期时间的键并删除其中已过期的键
+// 返回实际抽样的数量和删除的数量
+func (db *EchoDB) expireSample(count int) (sampled, expired int) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	now := time.Now()
+	if count > db.expires.Len() {
+		count = db.expires.Len()
+	}
+
+	for ; sampled < count; sampled++ {
+		key, ok := db.expires.Random()
+		if !ok {
+			break
+		}
+		if item, exists := db.data.Get(key); exists && item.expired(now) {
+			db.expireKey(key)
+			expired++
+		}
+	}
+
+	return sampled, expired
+}
@@ -6,38 +6,167 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"sync"
 	"time"
 )
 
+const (
+	gossipBatchSize = 1000   // 每次向一个节点发送的最大变更数量
+	gossipQueueSize = 100000 // 每个节点最多积压的键数量，超出时丢弃最早的键，由反熵同步修复
+)
+
 type GossipEngine struct {
-	peers       []string
+	members     *membership // SWIM 成员列表，复制和同步的对象是其中未死亡的成员
 	port        int
 	nodeID      string
 	lastGossip  time.Time
 	gossipState string
+
+	mutex   sync.Mutex               // 保护状态和待发送的变更
+	pending map[string]*pendingQueue // 每个节点待发送的键，发送失败时保留到下一轮重试
+	replica replica                  // 本节点的数据，处理复制来的变更和同步请求
+}
+
+// pendingQueue 是等待发送给一个节点的键，按写入的先后排列，同一个键只保留一项
+// 队列中只记录键，发送时才读取键当前的状态，同一个键的多次写入合并为一次发送
+type pendingQueue struct {
+	keys   []string
+	queued map[string]struct{}
+}
+
+// replica 是 GossipEngine 复制和同步的本地数据
+type replica interface {
+	handleMutations(mutations []Mutation)         // 应用其他节点复制来的变更
+	rangeDigests(ranges []KeyRange) []RangeDigest // 计算区间的摘要
+	splitRange(r KeyRange, parts int) []KeyRange  // 按本地的键把区间拆分为大致等大的子区间
+	rangeMutations(ranges []KeyRange) []Mutation  // 导出区间内的数据和删除标记
+	rangeEvicted(ranges []KeyRange) []Mutation    // 导出区间内被淘汰的键的版本
+	currentMutations(keys []string) []Mutation    // 导出键当前的数据或删除标记
+	exclusive(fn func())                          // 在与本地写入互斥的情况下执行 fn
 }
 
 // GossipState 定义节点的状态结构
 type GossipState struct {
-	NodeID     string `json:"node_id"`
-	LastGossip int64  `json:"last_gossip"`
-	State      string `json:"state"`
+	NodeID     string     `json:"node_id"`
+	LastGossip int64      `json:"last_gossip"`
+	State      string     `json:"state"`
+	Mutations  []Mutation `json:"mutations,omitempty"` // 复制给对方的写入
 }
 
-// NewGossipEngine 创建一个新的Gossip引擎实例
-func NewGossipEngine(peers []string, port int, nodeID string) *GossipEngine {
-	return &GossipEngine{
-		peers:       peers,
+// NewGossipEngine 创建一个新的Gossip引擎实例，peers 是初始的成员地址，address 是其他节点访问本节点的地址，为空时使用 localhost
+func NewGossipEngine(peers []string, port int, nodeID string, address string) *GossipEngine {
+	if address == "" {
+		address = fmt.Sprintf("localhost:%d", port)
+	}
+	// 以启动时间作为初始的 incarnation，重启后的节点可以覆盖其他成员记录的旧状态
+	self := Member{Address: address, NodeID: nodeID, State: MemberAlive, Incarnation: uint64(time.Now().UnixMilli()), Since: time.Now()}
+
+	g := &GossipEngine{
+		members:     newMembership(self, peers),
 		port:        port,
 		nodeID:      nodeID,
 		lastGossip:  time.Now(),
 		gossipState: fmt.Sprintf("Node %s is running", nodeID),
+		pending:     make(map[string]*pendingQueue),
+	}
+	g.members.subscribe(g.onMemberChange)
+	return g
+}
+
+// attach 设置引擎复制和同步的本地数据
+func (g *GossipEngine) attach(replica replica) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.replica = replica
+}
+
+// local 返回引擎复制和同步的本地数据，尚未设置时为 nil
+func (g *GossipEngine) local() replica {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.replica
+}
+
+// Broadcast 把被修改的键加入每个节点的发送队列，在下一轮 Gossip 时发送键当时的状态，离开集群后不再复制
+// 调用方通常持有数据库的写锁，这里只记录键，不复制值
+func (g *GossipEngine) Broadcast(key string) {
+	if g.members.left() {
+		return
+	}
+	peers := g.members.peers()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, peer := range peers {
+		queue, exists := g.pending[peer]
+		if !exists {
+			queue = &pendingQueue{queued: make(map[string]struct{})}
+			g.pending[peer] = queue
+		}
+		queue.push(key)
+	}
+}
+
+// push 把键追加到队列末尾，键已在队列中时保留原来的位置，队列已满时丢弃最早的键
+func (q *pendingQueue) push(keys ...string) {
+	for _, key := range keys {
+		if _, exists := q.queued[key]; exists {
+			continue
+		}
+		q.keys = append(q.keys, key)
+		q.queued[key] = struct{}{}
 	}
+	if overflow := len(q.keys) - gossipQueueSize; overflow > 0 {
+		for _, key := range q.keys[:overflow] {
+			delete(q.queued, key)
+		}
+		q.keys = append(q.keys[:0], q.keys[overflow:]...)
+	}
+}
+
+// takePending 取出发给节点的下一批键
+func (g *GossipEngine) takePending(peer string) []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	queue, exists := g.pending[peer]
+	if !exists {
+		return nil
+	}
+	n := min(len(queue.keys), gossipBatchSize)
+	batch := append([]string(nil), queue.keys[:n]...)
+	for _, key := range batch {
+		delete(queue.queued, key)
+	}
+	queue.keys = queue.keys[n:]
+	return batch
+}
+
+// requeue 把发送失败的键放回队列头部
+func (g *GossipEngine) requeue(peer string, keys []string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	queue, exists := g.pending[peer]
+	if !exists {
+		queue = &pendingQueue{queued: make(map[string]struct{})}
+		g.pending[peer] = queue
+	}
+	rest := queue.keys
+	queue.keys = nil
+	clear(queue.queued)
+	queue.push(keys...)
+	queue.push(rest...)
 }
 
 // 启动Gossip服务
 func (g *GossipEngine) StartGossipServer() {
-	router := gin.Default()
+	// 探测和传播每个周期都会发生，不记录每个请求的访问日志
+	router := gin.New()
+	router.Use(gin.Recovery())
 
 	// Gossip 接口，用于接收来自其他节点的 Gossip 数据
 	router.POST("/gossip", func(c *gin.Context) {
@@ -48,16 +177,32 @@ func (g *GossipEngine) StartGossipServer() {
 		}
 
 		// 更新节点的 Gossip 状态
+		g.mutex.Lock()
 		g.lastGossip = time.Unix(receivedState.LastGossip, 0)
 		g.gossipState = receivedState.State
+		g.mutex.Unlock()
 
-		fmt.Printf("Received gossip from node %s: %s\n", receivedState.NodeID, receivedState.State)
+		// 应用对方复制来的写入
+		if replica := g.local(); replica != nil && len(receivedState.Mutations) > 0 {
+			replica.handleMutations(receivedState.Mutations)
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Gossip received successfully",
 		})
 	})
 
+	// 反熵同步接口，用于比较区间摘要和交换不一致区间的数据
+	router.POST("/gossip/digest", g.handleDigest)
+	router.POST("/gossip/repair", g.handleRepair)
+
+	// SWIM 探测接口，ping-req 请求本节点代为探测另一个成员
+	router.POST("/gossip/ping", g.handlePing)
+	router.POST("/gossip/ping-req", g.handlePing)
+
+	// 新节点通过种子节点加入集群的接口
+	router.POST("/gossip/join", g.handleJoin)
+
 	// 启动 HTTP 服务，使用新的端口
 	go func() {
 		err := router.Run(fmt.Sprintf(":%d", g.port)) // 启动服务器，确保 g.port 是修改后的端口
@@ -67,36 +212,67 @@ func (g *GossipEngine) StartGossipServer() {
 	}()
 }
 
-// Gossip 向其他节点传播数据
+// Gossip 向其他节点传播数据，同时分批发送积压的写入
 func (g *GossipEngine) Gossip() {
-	for _, peer := range g.peers {
-		fmt.Printf("Gossiping to peer: %s\n", peer)
-
-		// 向其他节点传播 Gossip 数据
-		gossipData := GossipState{
-			NodeID:     g.nodeID,
-			LastGossip: time.Now().Unix(),
-			State:      g.gossipState,
-		}
+	if g.members.left() {
+		return
+	}
+	g.flush()
+}
 
-		// 向其他节点发送 Gossip 数据
-		err := sendGossip(peer, gossipData)
-		if err != nil {
-			fmt.Printf("Failed to gossip to node %s: %v\n", peer, err)
+// flush 把积压的写入分批发送给每个成员，离开集群时也用它发送交接期间的写入
+func (g *GossipEngine) flush() {
+	replica := g.local()
+	for _, peer := range g.members.peers() {
+		for {
+			// 向其他节点传播 Gossip 数据，积压的键在发送时读取当前的状态
+			g.mutex.Lock()
+			state := g.gossipState
+			g.mutex.Unlock()
+			keys := g.takePending(peer)
+			gossipData := GossipState{
+				NodeID:     g.nodeID,
+				LastGossip: time.Now().Unix(),
+				State:      state,
+			}
+			if replica != nil && len(keys) > 0 {
+				gossipData.Mutations = replica.currentMutations(keys)
+			}
+
+			// 向其他节点发送 Gossip 数据，失败的键留到下一轮重试
+			err := sendGossip(peer, gossipData)
+			if err != nil {
+				fmt.Printf("Failed to gossip to node %s: %v\n", peer, err)
+				g.requeue(peer, keys)
+				break
+			}
+			if len(keys) < gossipBatchSize {
+				break
+			}
 		}
 	}
 }
 
 // 向指定节点发送 Gossip 数据
 func sendGossip(peer string, data GossipState) error {
+	return postGossip(peer, "/gossip", data, nil)
+}
+
+// postGossip 向节点的 Gossip 服务发送 JSON 请求，response 不为 nil 时解析响应体
+func postGossip(peer, path string, request, response interface{}) error {
+	return postGossipTimeout(peer, path, request, response, 10*time.Second)
+}
+
+// postGossipTimeout 与 postGossip 相同，但使用指定的超时时间
+func postGossipTimeout(peer, path string, request, response interface{}, timeout time.Duration) error {
 	// 构建 HTTP 请求
-	url := fmt.Sprintf("http://%s/gossip", peer)
+	url := fmt.Sprintf("http://%s%s", peer, path)
 	client := &http.Client{
-		Timeout: 10 * time.Second, // 设置请求超时时间
+		Timeout: timeout, // 设置请求超时时间
 	}
 
-	// 将 GossipState 编码为 JSON
-	jsonData, err := json.Marshal(data)
+	// 将请求编码为 JSON
+	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return fmt.Errorf("failed to marshal gossip data: %w", err)
 	}
@@ -112,5 +288,10 @@ func sendGossip(peer string, data GossipState) error {
 		return fmt.Errorf("received non-OK response from %s: %v", peer, resp.StatusCode)
 	}
 
+	if response != nil {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return fmt.Errorf("failed to decode response from %s: %w", peer, err)
+		}
+	}
 	return nil
 }
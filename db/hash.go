@@ -0,0 +1,142 @@
+package db
+
+import (
+	"fmt"
+	"maps"
+	"time"
+)
+
+// HSet 写入哈希的一个或多个字段，返回新增字段的数量
+// 键不存在时创建一个永不过期的哈希，已有键保留原来的过期时间
+func (db *EchoDB) HSet(key string, fields map[string]string) (int, error) {
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%w: at least one field is required", ErrInvalidValue)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	added, changed, err := db.hset(key, fields, time.Now())
+	if err != nil || !changed {
+		return added, err
+	}
+	return added, db.appendLog(aofRecord{Op: aofHSet, Key: key, Args: hsetArgs(fields)})
+}
+
+// hsetArgs 把字段和值交替排列为 hset 记录的参数
+func hsetArgs(fields map[string]string) []string {
+	args := make([]string, 0, 2*len(fields))
+	for field, value := range fields {
+		args = append(args, field, value)
+	}
+	return args
+}
+
+// HGet 返回哈希中一个字段的值
+func (db *EchoDB) HGet(key, field string) (string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.readCollection(key, TypeHash, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if item == nil {
+		return "", ErrKeyNotFound
+	}
+
+	value, exists := item.Value.hash[field]
+	if !exists {
+		return "", ErrFieldNotFound
+	}
+	return value, nil
+}
+
+// HDel 删除哈希中的字段，返回实际删除的数量，最后一个字段被删除后键也会被删除
+func (db *EchoDB) HDel(key string, fields ...string) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	removed, err := db.hdel(key, fields, time.Now())
+	if err != nil || removed == 0 {
+		return removed, err
+	}
+	return removed, db.appendLog(aofRecord{Op: aofHDel, Key: key, Args: fields})
+}
+
+// HGetAll 返回哈希的所有字段，键不存在时返回空的哈希
+func (db *EchoDB) HGetAll(key string) (map[string]string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.readCollection(key, TypeHash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return map[string]string{}, nil
+	}
+	return maps.Clone(item.Value.hash), nil
+}
+
+// hset 是 HSet 的实现，返回新增字段的数量以及哈希是否被修改，调用方必须持有写锁
+func (db *EchoDB) hset(key string, fields map[string]string, now time.Time) (int, bool, error) {
+	growth := func(value Value) int64 {
+		var delta int64
+		for field, element := range fields {
+			if old, exists := value.hash[field]; exists {
+				delta += int64(len(element) - len(old))
+			} else {
+				delta += hashFieldSize(field, element)
+			}
+		}
+		return delta
+	}
+
+	item, err := db.growCollection(key, TypeHash, now, growth)
+	if err != nil {
+		return 0, false, err
+	}
+
+	delta, added, changed := growth(item.Value), 0, false
+	for field, element := range fields {
+		old, exists := item.Value.hash[field]
+		if !exists {
+			added++
+		}
+		if !exists || old != element {
+			changed = true
+		}
+		item.Value.hash[field] = element
+	}
+	db.resize(key, item, delta, changed)
+	return added, changed, nil
+}
+
+// hdel 是 HDel 的实现，调用方必须持有写锁
+func (db *EchoDB) hdel(key string, fields []string, now time.Time) (int, error) {
+	item, err := db.readCollection(key, TypeHash, now)
+	if err != nil || item == nil {
+		return 0, err
+	}
+
+	var delta int64
+	removed := 0
+	for _, field := range fields {
+		if old, exists := item.Value.hash[field]; exists {
+			delta -= hashFieldSize(field, old)
+			delete(item.Value.hash, field)
+			removed++
+		}
+	}
+	if removed > 0 {
+		db.resize(key, item, delta, true)
+	}
+	return removed, nil
+}
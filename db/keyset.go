@@ -0,0 +1,57 @@
+package db
+
+import "math/rand/v2"
+
+// keySet 是支持 O(1) 增删和随机取样的键集合
+// 键存放在切片中，index 记录每个键在切片中的位置，删除时用末尾元素填补空位
+type keySet struct {
+	keys  []string
+	index map[string]int
+}
+
+// newKeySet 创建一个空的键集合
+func newKeySet() *keySet {
+	return &keySet{index: make(map[string]int)}
+}
+
+// Add 向集合中加入键，已存在时不做处理
+func (s *keySet) Add(key string) {
+	if _, exists := s.index[key]; exists {
+		return
+	}
+	s.index[key] = len(s.keys)
+	s.keys = append(s.keys, key)
+}
+
+// Remove 从集合中移除键
+func (s *keySet) Remove(key string) {
+	i, exists := s.index[key]
+	if !exists {
+		return
+	}
+
+	last := len(s.keys) - 1
+	s.keys[i] = s.keys[last]
+	s.index[s.keys[i]] = i
+	s.keys = s.keys[:last]
+	delete(s.index, key)
+}
+
+// Contains 判断键是否在集合中
+func (s *keySet) Contains(key string) bool {
+	_, exists := s.index[key]
+	return exists
+}
+
+// Len 返回集合中键的数量
+func (s *keySet) Len() int {
+	return len(s.keys)
+}
+
+// Random 随机返回集合中的一个键，集合为空时返回 false
+func (s *keySet) Random() (string, bool) {
+	if len(s.keys) == 0 {
+		return "", false
+	}
+	return s.keys[rand.IntN(len(s.keys))], true
+}
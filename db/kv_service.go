@@ -0,0 +1,411 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KVResponse 键值接口的统一响应结构体
+type KVResponse struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// PutRequest 定义写入键值的请求体
+type PutRequest struct {
+	Type  ValueType       `json:"type"`                                          // 值的类型，省略时根据 value 推断
+	Value json.RawMessage `json:"value" binding:"required" swaggertype:"object"` // 值的内容，格式由 type 决定
+	TTL   *int64          `json:"ttl"`                                           // 过期秒数，省略时使用默认过期时间，0 表示永不过期
+}
+
+// value 解析请求中的值，未指定类型时根据 JSON 内容推断
+func (request PutRequest) value() (Value, error) {
+	if request.Type == "" {
+		return ParseJSONValue(request.Value)
+	}
+	return DecodeValue(request.Type, request.Value)
+}
+
+// ExpireRequest 定义设置过期时间的请求体
+type ExpireRequest struct {
+	TTL *int64 `json:"ttl" binding:"required"` // 过期秒数，不为正数时立即删除该键
+}
+
+// VersionData 定义写入后的版本号
+type VersionData struct {
+	Key     string `json:"key"`
+	Version uint64 `json:"version"`
+}
+
+// TypeData 定义值类型的返回数据
+type TypeData struct {
+	Key  string    `json:"key"`
+	Type ValueType `json:"type"`
+}
+
+// TTLData 定义剩余存活时间的返回数据
+type TTLData struct {
+	Key string `json:"key"`
+	TTL int64  `json:"ttl"` // 剩余秒数，永不过期时为 -1
+}
+
+// 范围查询每页返回条目数的默认值和上限
+const (
+	defaultScanLimit = 100
+	maxScanLimit     = 1000
+)
+
+// ttlSeconds 把请求中的过期秒数转换为时长，超出 time.Duration 的表示范围时返回 ErrInvalidTTL
+func ttlSeconds(seconds int64) (time.Duration, error) {
+	if seconds > math.MaxInt64/int64(time.Second) || seconds < math.MinInt64/int64(time.Second) {
+		return 0, fmt.Errorf("%w: ttl %d is out of range", ErrInvalidTTL, seconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// respondError 根据错误类型返回对应的状态码
+func respondError(context *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrKeyNotFound), errors.Is(err, ErrFieldNotFound), errors.Is(err, ErrMemberNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalidTTL), errors.Is(err, ErrInvalidCursor), errors.Is(err, ErrInvalidValue), errors.Is(err, ErrInvalidCount):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrWrongType), errors.Is(err, ErrNotInteger), errors.Is(err, ErrNotFloat), errors.Is(err, ErrOverflow):
+		status = http.StatusConflict
+	case errors.Is(err, ErrOutOfMemory):
+		status = http.StatusInsufficientStorage
+	case errors.Is(err, ErrSaveInProgress), errors.Is(err, ErrRewriteInProgress), errors.Is(err, ErrAOFDisabled),
+		errors.Is(err, ErrGossipDisabled):
+		status = http.StatusConflict
+	case errors.Is(err, ErrKeyExists), errors.Is(err, ErrVersionMismatch), errors.Is(err, ErrTxAborted):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, ErrJoinFailed), errors.Is(err, ErrHandoffFailed), errors.Is(err, ErrNodeLeft):
+		status = http.StatusServiceUnavailable
+	}
+
+	context.JSON(status, KVResponse{
+		Code:    strconv.Itoa(status),
+		Message: err.Error(),
+	})
+}
+
+// GetKey 查询单个键
+// @Summary 查询键值
+// @Description 根据键名返回对应的值以及过期时间、访问统计、版本号等元数据，版本号同时通过 ETag 响应头返回。
+// @Description 开启 Gossip 时返回的 stamp 和 clock 是键的 HLC 时间戳和版本向量；按 siblings 解决冲突时，siblings 中是与当前值并发写入的其他值，重新写入该键即解决冲突。
+// @Tags kv
+// @Produce  json
+// @Param key path string true "键名"
+// @Success 200 {object} KVResponse "查询成功"
+// @Header 200 {string} ETag "键的版本号"
+// @Failure 404 {object} KVResponse "键不存在"
+// @Router /kv/{key} [get]
+func (db *EchoDB) GetKey(context *gin.Context) {
+	key := context.Param("key")
+
+	item, exists := db.Query(key)
+	if !exists {
+		respondError(context, ErrKeyNotFound)
+		return
+	}
+
+	context.Header("ETag", formatETag(item.Version))
+	respondSuccess(context, Entry{Key: key, Item: *item})
+}
+
+// PutKey 写入或更新单个键
+// @Summary 写入键值
+// @Description 写入新键或覆盖已有键的值。type 可选 string、int、float、bytes、list、hash、set、zset，省略时根据 value 推断；可通过 ttl 指定过期秒数，0 表示永不过期。
+// @Description 条件写入：If-None-Match: * 仅在键不存在时写入，If-Match: * 仅在键存在时写入，If-Match: "版本号" 仅在版本号一致时写入，条件不满足时返回 412。
+// @Tags kv
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body PutRequest true "写入的值"
+// @Param If-Match header string false "* 或期望的版本号"
+// @Param If-None-Match header string false "只支持 *"
+// @Success 200 {object} KVResponse "写入成功"
+// @Header 200 {string} ETag "写入后的版本号"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 412 {object} KVResponse "写入条件不满足"
+// @Router /kv/{key} [put]
+func (db *EchoDB) PutKey(context *gin.Context) {
+	key := context.Param("key")
+
+	var request PutRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	value, err := request.value()
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	ttl := db.lifetime
+	if request.TTL != nil {
+		if ttl, err = ttlSeconds(*request.TTL); err != nil {
+			respondError(context, err)
+			return
+		}
+	}
+
+	var version uint64
+	ifMatch, ifNoneMatch := context.GetHeader("If-Match"), context.GetHeader("If-None-Match")
+	switch {
+	case ifMatch != "" && ifNoneMatch != "":
+		respondInvalidInput(context)
+		return
+	case ifNoneMatch == "*":
+		version, err = db.InsertNX(key, value, ttl)
+	case ifNoneMatch != "":
+		respondInvalidInput(context)
+		return
+	case ifMatch == "*":
+		version, err = db.InsertXX(key, value, ttl)
+		// 与 HTTP 的语义一致，If-Match 的键不存在时同样是条件不满足
+		if errors.Is(err, ErrKeyNotFound) {
+			err = ErrVersionMismatch
+		}
+	case ifMatch != "":
+		expected, parseErr := parseETag(ifMatch)
+		if parseErr != nil {
+			respondInvalidInput(context)
+			return
+		}
+		version, err = db.CompareAndSwap(key, expected, value, ttl)
+	default:
+		version, err = db.insertIf(key, value, ttl, nil)
+	}
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	context.Header("ETag", formatETag(version))
+	respondSuccess(context, VersionData{Key: key, Version: version})
+}
+
+// formatETag 把版本号格式化为 ETag
+func formatETag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// parseETag 从 If-Match 请求头中解析版本号，引号可以省略
+func parseETag(tag string) (uint64, error) {
+	tag = strings.TrimSpace(tag)
+	if unquoted, err := strconv.Unquote(tag); err == nil {
+		tag = unquoted
+	}
+	return strconv.ParseUint(tag, 10, 64)
+}
+
+// DeleteKey 删除单个键
+// @Summary 删除键值
+// @Description 删除指定的键。
+// @Tags kv
+// @Produce  json
+// @Param key path string true "键名"
+// @Success 200 {object} KVResponse "删除成功"
+// @Failure 404 {object} KVResponse "键不存在"
+// @Router /kv/{key} [delete]
+func (db *EchoDB) DeleteKey(context *gin.Context) {
+	key := context.Param("key")
+
+	if err := db.Delete(key); err != nil {
+		respondError(context, err)
+		return
+	}
+
+	respondSuccess(context, nil)
+}
+
+// ExpireKey 为键设置过期时间
+// @Summary 设置过期时间
+// @Description 为已有键设置新的过期秒数，不为正数时立即删除该键。
+// @Tags kv
+// @Accept  json
+// @Produce  json
+// @Param key path string true "键名"
+// @Param body body ExpireRequest true "过期秒数"
+// @Success 200 {object} KVResponse "设置成功"
+// @Failure 400 {object} KVResponse "无效的输入数据"
+// @Failure 404 {object} KVResponse "键不存在"
+// @Router /kv/{key}/expire [post]
+func (db *EchoDB) ExpireKey(context *gin.Context) {
+	key := context.Param("key")
+
+	var request ExpireRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	ttl, err := ttlSeconds(*request.TTL)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+	if err := db.Expire(key, ttl); err != nil {
+		respondError(context, err)
+		return
+	}
+
+	respondSuccess(context, nil)
+}
+
+// PersistKey 移除键的过期时间
+// @Summary 移除过期时间
+// @Description 移除已有键的过期时间，使其永不过期。
+// @Tags kv
+// @Produce  json
+// @Param key path string true "键名"
+// @Success 200 {object} KVResponse "设置成功"
+// @Failure 404 {object} KVResponse "键不存在"
+// @Router /kv/{key}/persist [post]
+func (db *EchoDB) PersistKey(context *gin.Context) {
+	key := context.Param("key")
+
+	if err := db.Persist(key); err != nil {
+		respondError(context, err)
+		return
+	}
+
+	respondSuccess(context, nil)
+}
+
+// GetTTL 查询键的剩余存活时间
+// @Summary 查询剩余存活时间
+// @Description 返回键的剩余存活秒数，永不过期的键返回 -1。
+// @Tags kv
+// @Produce  json
+// @Param key path string true "键名"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 404 {object} KVResponse "键不存在"
+// @Router /kv/{key}/ttl [get]
+func (db *EchoDB) GetTTL(context *gin.Context) {
+	key := context.Param("key")
+
+	remaining, err := db.TTL(key)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	seconds := int64(-1)
+	if remaining != NoExpiration {
+		// 与 Redis 一致，按四舍五入换算为秒
+		seconds = int64((remaining + time.Second/2) / time.Second)
+	}
+
+	respondSuccess(context, TTLData{Key: key, TTL: seconds})
+}
+
+// GetType 查询键的值类型
+// @Summary 查询值类型
+// @Description 返回键对应的值的类型：string、int、float、bytes、list、hash、set 或 zset。
+// @Tags kv
+// @Produce  json
+// @Param key path string true "键名"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 404 {object} KVResponse "键不存在"
+// @Router /kv/{key}/type [get]
+func (db *EchoDB) GetType(context *gin.Context) {
+	key := context.Param("key")
+
+	kind, err := db.Type(key)
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	respondSuccess(context, TypeData{Key: key, Type: kind})
+}
+
+// RangeKeys 范围查询
+// @Summary 范围查询
+// @Description 按键的顺序分页返回数据项。指定 prefix 时返回带有该前缀的键，否则返回 [start, end] 区间内的键，end 为空时不设上界；两种方式都可以通过 reverse 按降序返回。
+// @Description 返回的 next_cursor 不为空时，携带该游标再次请求即可获取下一页。
+// @Tags kv
+// @Produce  json
+// @Param start query string false "起始键（包含）"
+// @Param end query string false "结束键（包含）"
+// @Param prefix query string false "键前缀"
+// @Param reverse query bool false "是否按降序返回"
+// @Param limit query int false "每页最多返回的条目数" default(100)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} KVResponse "查询成功"
+// @Failure 400 {object} KVResponse "请求参数错误"
+// @Router /kv [get]
+func (db *EchoDB) RangeKeys(context *gin.Context) {
+	start := context.Query("start")
+	end := context.Query("end")
+	prefix := context.Query("prefix")
+	cursor := context.Query("cursor")
+
+	reverse, err := strconv.ParseBool(context.DefaultQuery("reverse", "false"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, KVResponse{
+			Code:    "400",
+			Message: "reverse must be a boolean",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(context.DefaultQuery("limit", strconv.Itoa(defaultScanLimit)))
+	if err != nil || limit <= 0 || limit > maxScanLimit {
+		context.JSON(http.StatusBadRequest, KVResponse{
+			Code:    "400",
+			Message: "limit must be between 1 and " + strconv.Itoa(maxScanLimit),
+		})
+		return
+	}
+
+	if end != "" && start > end {
+		context.JSON(http.StatusBadRequest, KVResponse{
+			Code:    "400",
+			Message: "start must not be greater than end",
+		})
+		return
+	}
+
+	var result ScanResult
+	switch {
+	case prefix != "" && reverse:
+		result, err = db.ReversePrefix(prefix, cursor, limit)
+	case prefix != "":
+		result, err = db.Prefix(prefix, cursor, limit)
+	case reverse:
+		result, err = db.ReverseRange(start, end, cursor, limit)
+	default:
+		result, err = db.Range(start, end, cursor, limit)
+	}
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	respondSuccess(context, result)
+}
+
+// GetStats 查询容量与淘汰统计
+// @Summary 查询统计信息
+// @Description 返回键数量、估算的内存占用、容量上限以及淘汰和过期的累计数量。
+// @Tags stats
+// @Produce  json
+// @Success 200 {object} KVResponse "查询成功"
+// @Router /stats [get]
+func (db *EchoDB) GetStats(context *gin.Context) {
+	respondSuccess(context, db.Stats())
+}
@@ -0,0 +1,323 @@
+package db
+
+import (
+	"container/heap"
+	"math/rand/v2"
+	"time"
+)
+
+// 对数计数器的参数，取值参考 Redis 的 LFU 实现
+const (
+	lfuInitValue  = 5   // 新键的初始计数，避免刚写入的键立即被淘汰
+	lfuMaxCounter = 255 // 计数器上限
+)
+
+// lfuLogIncr 以对数概率增加计数器
+// 计数越大，增加的概率越低：p = 1 / ((counter - lfuInitValue) * logFactor + 1)，
+// 因此 8 位的计数器即可区分从几次到上百万次的访问量
+func lfuLogIncr(counter, logFactor int) int {
+	if counter >= lfuMaxCounter {
+		return lfuMaxCounter
+	}
+
+	base := max(counter-lfuInitValue, 0)
+	if rand.Float64() < 1/float64(base*logFactor+1) {
+		counter++
+	}
+	return counter
+}
+
+// lfuDecay 返回按时间衰减后的计数器
+// 距离上次访问每经过一个 decayTime 周期计数减一，decayTime 不为正数时不衰减
+func lfuDecay(counter int, lastAccessed, now time.Time, decayTime time.Duration) int {
+	if decayTime <= 0 {
+		return counter
+	}
+
+	periods := int(now.Sub(lastAccessed) / decayTime)
+	if periods >= counter {
+		return 0
+	}
+	return counter - periods
+}
+
+// lfuNode 是 LFU 结构中代表一个键的链表节点
+type lfuNode struct {
+	key    string
+	item   *Item
+	bucket *lfuBucket
+	prev   *lfuNode
+	next   *lfuNode
+}
+
+// lfuBucket 存放计数器相同的键
+// 键按进入该桶的时间排列，也就是按最后访问时间从旧到新排列，头部是最久未访问的键
+type lfuBucket struct {
+	frequency int
+	head      *lfuNode
+	tail      *lfuNode
+	prev      *lfuBucket // 计数更低的相邻桶
+	next      *lfuBucket // 计数更高的相邻桶
+}
+
+// lfuCache 是 O(1) 的 LFU 淘汰结构
+// 键按数据项的对数计数器分桶，桶按计数升序组成双向链表。计数器最多只有 256 个取值，
+// 因此定位桶和选取淘汰对象的开销都有固定上限，与键的总数无关。
+// 计数器会随时间衰减，而同一个桶中最久未访问的键衰减得最多，所以只需比较每个桶的头部
+// 衰减后的计数，就能找到全局衰减后计数最低的键；计数相同时淘汰最久未访问的键
+type lfuCache struct {
+	nodes     map[string]*lfuNode
+	buckets   map[int]*lfuBucket
+	head      *lfuBucket    // 计数最低的桶
+	decayTime time.Duration // 计数器衰减一次的周期
+}
+
+// newLFUCache 创建一个空的 LFU 结构
+func newLFUCache(decayTime time.Duration) *lfuCache {
+	return &lfuCache{
+		nodes:     make(map[string]*lfuNode),
+		buckets:   make(map[int]*lfuBucket),
+		decayTime: decayTime,
+	}
+}
+
+// OnInsert 记录一个新键，键已存在时视为一次访问
+func (c *lfuCache) OnInsert(key string, item *Item) {
+	if _, exists := c.nodes[key]; exists {
+		c.OnAccess(key, item)
+		return
+	}
+
+	node := &lfuNode{key: key, item: item}
+	c.bucket(item.Frequency).push(node)
+	c.nodes[key] = node
+}
+
+// OnAccess 记录一次访问，把键移动到与其当前计数对应的桶的尾部
+func (c *lfuCache) OnAccess(key string, item *Item) {
+	node, exists := c.nodes[key]
+	if !exists {
+		return
+	}
+
+	current := node.bucket
+	current.remove(node)
+	node.item = item
+	c.bucket(item.Frequency).push(node)
+	if current.head == nil {
+		c.removeBucket(current)
+	}
+}
+
+// OnDelete 删除一个键
+func (c *lfuCache) OnDelete(key string) {
+	node, exists := c.nodes[key]
+	if !exists {
+		return
+	}
+
+	bucket := node.bucket
+	bucket.remove(node)
+	if bucket.head == nil {
+		c.removeBucket(bucket)
+	}
+	delete(c.nodes, key)
+}
+
+// Victim 返回应当被淘汰的键：衰减后计数最低的键中最久未访问的一个
+func (c *lfuCache) Victim() (string, bool) {
+	now := time.Now()
+
+	var victim *lfuNode
+	lowest := 0
+	for bucket := c.head; bucket != nil; bucket = bucket.next {
+		// 计数未衰减时，更高的桶不可能出现更低的计数
+		if victim != nil && c.decayTime <= 0 {
+			break
+		}
+
+		candidate := bucket.head
+		counter := lfuDecay(bucket.frequency, candidate.item.LastAccessed, now, c.decayTime)
+		if victim == nil || counter < lowest ||
+			(counter == lowest && candidate.item.LastAccessed.Before(victim.item.LastAccessed)) {
+			victim, lowest = candidate, counter
+		}
+	}
+
+	if victim == nil {
+		return "", false
+	}
+	return victim.key, true
+}
+
+// bucket 返回指定计数的桶，不存在时按顺序插入一个新桶
+func (c *lfuCache) bucket(frequency int) *lfuBucket {
+	if bucket, exists := c.buckets[frequency]; exists {
+		return bucket
+	}
+
+	// 找到最后一个计数小于 frequency 的桶，新桶插在它之后
+	var prev *lfuBucket
+	for next := c.head; next != nil && next.frequency < frequency; next = next.next {
+		prev = next
+	}
+
+	bucket := &lfuBucket{frequency: frequency, prev: prev}
+	if prev == nil {
+		bucket.next = c.head
+		c.head = bucket
+	} else {
+		bucket.next = prev.next
+		prev.next = bucket
+	}
+	if bucket.next != nil {
+		bucket.next.prev = bucket
+	}
+	c.buckets[frequency] = bucket
+	return bucket
+}
+
+// removeBucket 从链表中移除一个空桶
+func (c *lfuCache) removeBucket(bucket *lfuBucket) {
+	if bucket.prev == nil {
+		c.head = bucket.next
+	} else {
+		bucket.prev.next = bucket.next
+	}
+	if bucket.next != nil {
+		bucket.next.prev = bucket.prev
+	}
+	delete(c.buckets, bucket.frequency)
+}
+
+// push 把节点追加到桶的尾部
+func (b *lfuBucket) push(node *lfuNode) {
+	node.bucket = b
+	node.prev = b.tail
+	node.next = nil
+	if b.tail == nil {
+		b.head = node
+	} else {
+		b.tail.next = node
+	}
+	b.tail = node
+}
+
+// remove 把节点从桶中摘除
+func (b *lfuBucket) remove(node *lfuNode) {
+	if node.prev == nil {
+		b.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next == nil {
+		b.tail = node.prev
+	} else {
+		node.next.prev = node.prev
+	}
+	node.prev, node.next, node.bucket = nil, nil, nil
+}
+
+// lfuAgingEntry 是 LFU-Aging 策略中的一个键
+type lfuAgingEntry struct {
+	key       string
+	frequency int
+	priority  int    // 淘汰优先级：访问频率加上该键最后一次被访问时的缓存年龄
+	sequence  uint64 // 最后一次访问的序号，优先级相同时先淘汰更早访问的键
+	index     int    // 在堆中的位置
+}
+
+// lfuAgingHeap 是按优先级排列的最小堆
+type lfuAgingHeap []*lfuAgingEntry
+
+func (h lfuAgingHeap) Len() int { return len(h) }
+
+func (h lfuAgingHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].sequence < h[j].sequence
+}
+
+func (h lfuAgingHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuAgingHeap) Push(x any) {
+	entry := x.(*lfuAgingEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuAgingHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// lfuAgingPolicy 是带动态老化的 LFU（LFU-DA）
+// 缓存维护一个年龄，每次淘汰时把年龄提升为被淘汰键的优先级；键被访问时的优先级为访问频率加当前年龄。
+// 这样很久以前积累了大量访问的键，其优先级会逐渐被新近活跃的键超过，最终得以淘汰
+type lfuAgingPolicy struct {
+	entries  map[string]*lfuAgingEntry
+	heap     lfuAgingHeap
+	age      int
+	sequence uint64
+	victim   string // 最近一次 Victim 选出的键，删除时据此更新缓存年龄
+}
+
+func newLFUAgingPolicy() *lfuAgingPolicy {
+	return &lfuAgingPolicy{entries: make(map[string]*lfuAgingEntry)}
+}
+
+func (p *lfuAgingPolicy) OnInsert(key string, item *Item) {
+	if _, exists := p.entries[key]; exists {
+		p.OnAccess(key, item)
+		return
+	}
+
+	p.sequence++
+	entry := &lfuAgingEntry{key: key, frequency: 1, priority: p.age + 1, sequence: p.sequence}
+	p.entries[key] = entry
+	heap.Push(&p.heap, entry)
+}
+
+func (p *lfuAgingPolicy) OnAccess(key string, item *Item) {
+	entry, exists := p.entries[key]
+	if !exists {
+		return
+	}
+
+	p.sequence++
+	entry.frequency++
+	entry.priority = p.age + entry.frequency
+	entry.sequence = p.sequence
+	heap.Fix(&p.heap, entry.index)
+}
+
+func (p *lfuAgingPolicy) OnDelete(key string) {
+	entry, exists := p.entries[key]
+	if !exists {
+		return
+	}
+
+	// 只有被淘汰的键才推动缓存年龄，主动删除和过期不影响
+	if key == p.victim {
+		p.age = entry.priority
+		p.victim = ""
+	}
+	heap.Remove(&p.heap, entry.index)
+	delete(p.entries, key)
+}
+
+func (p *lfuAgingPolicy) Victim() (string, bool) {
+	if len(p.heap) == 0 {
+		return "", false
+	}
+	p.victim = p.heap[0].key
+	return p.victim, true
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// listElementSize 估算列表中一个元素占用的内存
+func listElementSize(element string) int64 {
+	return stringOverhead + int64(len(element))
+}
+
+// LPush 把元素依次插入列表头部，返回插入后列表的长度
+// 与 Redis 一致，LPush(key, "a", "b") 之后列表以 "b", "a" 开头。
+// 键不存在时创建一个永不过期的列表，已有键保留原来的过期时间
+func (db *EchoDB) LPush(key string, elements ...string) (int, error) {
+	return db.pushAndLog(key, elements, true)
+}
+
+// RPush 把元素依次追加到列表尾部，返回追加后列表的长度
+func (db *EchoDB) RPush(key string, elements ...string) (int, error) {
+	return db.pushAndLog(key, elements, false)
+}
+
+// LPop 从列表头部弹出最多 count 个元素，键不存在时返回空切片
+// 最后一个元素被弹出后键也会被删除
+func (db *EchoDB) LPop(key string, count int) ([]string, error) {
+	return db.popAndLog(key, count, true)
+}
+
+// RPop 从列表尾部弹出最多 count 个元素，键不存在时返回空切片
+func (db *EchoDB) RPop(key string, count int) ([]string, error) {
+	return db.popAndLog(key, count, false)
+}
+
+// LRange 返回列表中下标在 [start, stop] 之间的元素，负数下标从列表末尾倒数，-1 表示最后一个元素
+// 下标超出范围时按列表的边界截断，键不存在时返回空切片
+func (db *EchoDB) LRange(key string, start, stop int) ([]string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.readCollection(key, TypeList, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return []string{}, nil
+	}
+
+	start, stop, ok := normalizeRange(start, stop, len(item.Value.list))
+	if !ok {
+		return []string{}, nil
+	}
+	return slices.Clone(item.Value.list[start : stop+1]), nil
+}
+
+// pushAndLog 执行 LPush 或 RPush 并记录到 AOF
+func (db *EchoDB) pushAndLog(key string, elements []string, left bool) (int, error) {
+	if len(elements) == 0 {
+		return 0, fmt.Errorf("%w: at least one element is required", ErrInvalidValue)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	length, err := db.push(key, elements, left, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	op := aofRPush
+	if left {
+		op = aofLPush
+	}
+	return length, db.appendLog(aofRecord{Op: op, Key: key, Args: elements})
+}
+
+// popAndLog 执行 LPop 或 RPop 并记录到 AOF
+func (db *EchoDB) popAndLog(key string, count int, left bool) ([]string, error) {
+	if count <= 0 {
+		return nil, ErrInvalidCount
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return nil, err
+	}
+	popped, err := db.pop(key, count, left, time.Now())
+	if err != nil || len(popped) == 0 {
+		return popped, err
+	}
+
+	op := aofRPop
+	if left {
+		op = aofLPop
+	}
+	return popped, db.appendLog(aofRecord{Op: op, Key: key, Count: len(popped)})
+}
+
+// push 是 LPush 和 RPush 的实现，调用方必须持有写锁
+func (db *EchoDB) push(key string, elements []string, left bool, now time.Time) (int, error) {
+	var delta int64
+	for _, element := range elements {
+		delta += listElementSize(element)
+	}
+
+	item, err := db.growCollection(key, TypeList, now, func(Value) int64 { return delta })
+	if err != nil {
+		return 0, err
+	}
+
+	if left {
+		reversed := slices.Clone(elements)
+		slices.Reverse(reversed)
+		item.Value.list = slices.Insert(item.Value.list, 0, reversed...)
+	} else {
+		item.Value.list = append(item.Value.list, elements...)
+	}
+	db.resize(key, item, delta, true)
+	return len(item.Value.list), nil
+}
+
+// pop 是 LPop 和 RPop 的实现，调用方必须持有写锁
+func (db *EchoDB) pop(key string, count int, left bool, now time.Time) ([]string, error) {
+	item, err := db.readCollection(key, TypeList, now)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return []string{}, nil
+	}
+
+	list := item.Value.list
+	count = min(count, len(list))
+
+	var popped []string
+	if left {
+		popped = slices.Clone(list[:count])
+		item.Value.list = slices.Delete(list, 0, count)
+	} else {
+		popped = slices.Clone(list[len(list)-count:])
+		slices.Reverse(popped)
+		item.Value.list = slices.Delete(list, len(list)-count, len(list))
+	}
+
+	var delta int64
+	for _, element := range popped {
+		delta -= listElementSize(element)
+	}
+	db.resize(key, item, delta, true)
+	return popped, nil
+}
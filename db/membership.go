@@ -0,0 +1,524 @@
+package db
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	maxPiggyback   = 16 // 每条探测消息最多捎带的成员更新数量
+	retransmitMult = 3  // 每条成员更新被捎带的次数为 retransmitMult * log2(成员数 + 1)
+)
+
+// MemberState 是成员的状态
+type MemberState string
+
+const (
+	MemberAlive   MemberState = "alive"   // 正常响应探测
+	MemberSuspect MemberState = "suspect" // 直接和间接探测都失败，等待它反驳或超时
+	MemberDead    MemberState = "dead"    // 怀疑超时，不再探测和复制
+	MemberLeft    MemberState = "left"    // 主动离开集群，不再探测和复制
+)
+
+// rank 是状态在同一个 incarnation 下的优先级，优先级高的状态覆盖优先级低的状态
+func (state MemberState) rank() int {
+	switch state {
+	case MemberSuspect:
+		return 1
+	case MemberDead:
+		return 2
+	case MemberLeft:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// reachable 判断是否应当与处于该状态的成员通信，被怀疑的成员仍可能存活，因此也包括在内
+func (state MemberState) reachable() bool {
+	return state == MemberAlive || state == MemberSuspect
+}
+
+// Member 是集群中的一个节点
+type Member struct {
+	Address     string      `json:"address"`     // Gossip 服务的地址，成员以地址区分
+	NodeID      string      `json:"node_id"`     // 节点 ID，收到该节点的消息之前为空
+	State       MemberState `json:"state"`       // 成员状态
+	Incarnation uint64      `json:"incarnation"` // 只能由成员自己递增，用于反驳对它的怀疑
+	Since       time.Time   `json:"since"`       // 进入当前状态的时间
+}
+
+// MemberUpdate 是捎带在探测消息上传播的成员状态变化
+type MemberUpdate struct {
+	Address     string      `json:"address"`
+	NodeID      string      `json:"node_id,omitempty"`
+	State       MemberState `json:"state"`
+	Incarnation uint64      `json:"incarnation"`
+}
+
+// overrides 判断更新是否比成员当前的状态更新：incarnation 更大，或者相同但状态优先级更高
+func (update MemberUpdate) overrides(member *Member) bool {
+	if update.Incarnation != member.Incarnation {
+		return update.Incarnation > member.Incarnation
+	}
+	return update.State.rank() > member.State.rank()
+}
+
+// broadcast 是等待捎带的成员更新
+type broadcast struct {
+	update MemberUpdate
+	sent   int // 已经捎带的次数
+}
+
+// membership 维护 SWIM 协议的成员列表：每个周期按轮转顺序探测一个成员，
+// 成员状态的变化捎带在探测消息上以 Gossip 的方式传播
+type membership struct {
+	mutex      sync.Mutex
+	self       Member
+	members    map[string]*Member // 除自己以外的成员，按地址索引
+	queue      []*broadcast       // 等待捎带的成员更新，每个成员至多一条
+	probeOrder []string           // 本轮的探测顺序，每轮开始时重新打乱
+	listeners  []func(Member)     // 成员状态变化时的回调
+}
+
+// newMembership 创建成员列表，seeds 中的地址作为初始的存活成员
+func newMembership(self Member, seeds []string) *membership {
+	m := &membership{
+		self:    self,
+		members: make(map[string]*Member),
+	}
+	now := time.Now()
+	for _, address := range seeds {
+		if address != self.Address {
+			m.members[address] = &Member{Address: address, State: MemberAlive, Since: now}
+		}
+	}
+	m.enqueue(m.selfUpdate())
+	return m
+}
+
+// subscribe 注册成员状态变化的回调，回调在不持有锁的情况下调用
+func (m *membership) subscribe(listener func(Member)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.listeners = append(m.listeners, listener)
+}
+
+// notify 依次通知成员状态的变化
+func (m *membership) notify(changes []Member) {
+	m.mutex.Lock()
+	listeners := slices.Clone(m.listeners)
+	m.mutex.Unlock()
+
+	for _, change := range changes {
+		for _, listener := range listeners {
+			listener(change)
+		}
+	}
+}
+
+// selfUpdate 返回宣告自己当前状态的更新，调用方必须持有锁
+func (m *membership) selfUpdate() MemberUpdate {
+	return MemberUpdate{Address: m.self.Address, NodeID: m.self.NodeID, State: m.self.State, Incarnation: m.self.Incarnation}
+}
+
+// local 返回宣告自己当前状态的更新，附在每条探测消息上
+func (m *membership) local() MemberUpdate {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.selfUpdate()
+}
+
+// list 返回自己和按地址排序的其他成员
+func (m *membership) list() (Member, []Member) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	members := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		members = append(members, *member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Address < members[j].Address })
+	return m.self, members
+}
+
+// updates 返回自己和所有可以通信的成员的状态，发送给新加入的成员
+func (m *membership) updates() []MemberUpdate {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	updates := []MemberUpdate{m.selfUpdate()}
+	for address, member := range m.members {
+		if member.State.reachable() {
+			updates = append(updates, MemberUpdate{Address: address, NodeID: member.NodeID, State: member.State, Incarnation: member.Incarnation})
+		}
+	}
+	return updates
+}
+
+// left 判断本节点是否已经离开集群
+func (m *membership) left() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.self.State == MemberLeft
+}
+
+// leave 把自己标记为离开并递增 incarnation，使离开的宣告覆盖其他成员记录的存活状态
+func (m *membership) leave() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.self.State, m.self.Since = MemberLeft, time.Now()
+	m.self.Incarnation++
+	m.enqueue(m.selfUpdate())
+}
+
+// rejoin 让离开的节点重新标记为存活，递增 incarnation 覆盖离开的宣告
+func (m *membership) rejoin() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.self.State == MemberLeft {
+		m.self.State, m.self.Since = MemberAlive, time.Now()
+		m.self.Incarnation++
+		m.enqueue(m.selfUpdate())
+	}
+}
+
+// peers 返回可以通信的成员地址
+func (m *membership) peers() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var peers []string
+	for address, member := range m.members {
+		if member.State.reachable() {
+			peers = append(peers, address)
+		}
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// apply 合并收到的成员更新，返回状态发生变化的成员
+func (m *membership) apply(updates ...MemberUpdate) []Member {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var changes []Member
+	now := time.Now()
+	for _, update := range updates {
+		if update.Address == "" {
+			continue
+		}
+
+		// 有成员怀疑自己或宣告自己死亡时，递增 incarnation 反驳，已经离开时不再反驳
+		if update.Address == m.self.Address {
+			if m.self.State == MemberAlive && update.State != MemberAlive && update.Incarnation >= m.self.Incarnation {
+				m.self.Incarnation = update.Incarnation + 1
+				m.enqueue(m.selfUpdate())
+			}
+			continue
+		}
+
+		member, exists := m.members[update.Address]
+		if !exists {
+			// 不认识的成员已经死亡或离开时没有必要记录
+			if !update.State.reachable() {
+				continue
+			}
+			member = &Member{Address: update.Address}
+			m.members[update.Address] = member
+		} else if !update.overrides(member) {
+			if member.NodeID == "" && update.NodeID != "" {
+				member.NodeID = update.NodeID
+			}
+			continue
+		}
+
+		if update.NodeID != "" {
+			member.NodeID = update.NodeID
+		}
+		member.Incarnation = update.Incarnation
+		if member.State != update.State || !exists {
+			member.State = update.State
+			member.Since = now
+			changes = append(changes, *member)
+		}
+		m.enqueue(update)
+	}
+	return changes
+}
+
+// suspect 在直接和间接探测都失败后怀疑成员，返回状态发生变化的成员
+func (m *membership) suspect(address string) []Member {
+	m.mutex.Lock()
+	member, exists := m.members[address]
+	if !exists || member.State != MemberAlive {
+		m.mutex.Unlock()
+		return nil
+	}
+	update := MemberUpdate{Address: address, NodeID: member.NodeID, State: MemberSuspect, Incarnation: member.Incarnation}
+	m.mutex.Unlock()
+
+	return m.apply(update)
+}
+
+// expireSuspects 把怀疑时间超过 timeout 的成员判定为死亡，返回状态发生变化的成员
+func (m *membership) expireSuspects(now time.Time, timeout time.Duration) []Member {
+	m.mutex.Lock()
+	var updates []MemberUpdate
+	for address, member := range m.members {
+		if member.State == MemberSuspect && now.Sub(member.Since) >= timeout {
+			updates = append(updates, MemberUpdate{Address: address, NodeID: member.NodeID, State: MemberDead, Incarnation: member.Incarnation})
+		}
+	}
+	m.mutex.Unlock()
+
+	return m.apply(updates...)
+}
+
+// reap 移除进入死亡或离开状态超过 timeout 的成员，以及尚未发完的关于它们的更新，返回移除的数量
+// 被移除的成员之后再宣告存活时会被重新加入，timeout 不为正数时不移除
+func (m *membership) reap(now time.Time, timeout time.Duration) int {
+	if timeout <= 0 {
+		return 0
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	reaped := 0
+	for address, member := range m.members {
+		if !member.State.reachable() && now.Sub(member.Since) >= timeout {
+			delete(m.members, address)
+			reaped++
+		}
+	}
+	if reaped > 0 {
+		m.queue = slices.DeleteFunc(m.queue, func(pending *broadcast) bool {
+			_, exists := m.members[pending.update.Address]
+			return !exists && pending.update.Address != m.self.Address
+		})
+	}
+	return reaped
+}
+
+// nextProbe 返回本周期要探测的成员，所有成员按打乱的顺序轮流探测，每轮结束后重新打乱
+func (m *membership) nextProbe() (string, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for {
+		if len(m.probeOrder) == 0 {
+			for address, member := range m.members {
+				if member.State.reachable() {
+					m.probeOrder = append(m.probeOrder, address)
+				}
+			}
+			if len(m.probeOrder) == 0 {
+				return "", false
+			}
+			rand.Shuffle(len(m.probeOrder), func(i, j int) {
+				m.probeOrder[i], m.probeOrder[j] = m.probeOrder[j], m.probeOrder[i]
+			})
+		}
+
+		address := m.probeOrder[0]
+		m.probeOrder = m.probeOrder[1:]
+		if member, exists := m.members[address]; exists && member.State.reachable() {
+			return address, true
+		}
+	}
+}
+
+// helpers 随机选择至多 k 个除 target 以外的存活成员，请求它们间接探测 target
+func (m *membership) helpers(target string, k int) []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var candidates []string
+	for address, member := range m.members {
+		if address != target && member.State == MemberAlive {
+			candidates = append(candidates, address)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates[:min(k, len(candidates))]
+}
+
+// enqueue 加入一条待捎带的更新，替换同一成员尚未发完的旧更新，调用方必须持有锁
+func (m *membership) enqueue(update MemberUpdate) {
+	for i, pending := range m.queue {
+		if pending.update.Address == update.Address {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			break
+		}
+	}
+	m.queue = append(m.queue, &broadcast{update: update})
+}
+
+// piggyback 取出要捎带在下一条消息上的更新，优先选择捎带次数最少的
+// 每条更新捎带 retransmitMult * log2(成员数 + 1) 次后不再发送
+func (m *membership) piggyback() []MemberUpdate {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sort.SliceStable(m.queue, func(i, j int) bool { return m.queue[i].sent < m.queue[j].sent })
+	limit := retransmitMult * int(math.Ceil(math.Log2(float64(len(m.members)+2))))
+
+	updates := make([]MemberUpdate, 0, min(len(m.queue), maxPiggyback))
+	for _, pending := range m.queue[:min(len(m.queue), maxPiggyback)] {
+		updates = append(updates, pending.update)
+		pending.sent++
+	}
+	m.queue = slices.DeleteFunc(m.queue, func(pending *broadcast) bool {
+		return pending.sent >= limit
+	})
+	return updates
+}
+
+// PingRequest 是 SWIM 的探测消息，Target 不为空时请求对方代为探测 Target（ping-req）
+type PingRequest struct {
+	From    MemberUpdate   `json:"from"`              // 发送方自己的状态
+	Target  string         `json:"target,omitempty"`  // 间接探测的目标地址
+	Timeout int64          `json:"timeout,omitempty"` // 间接探测等待目标确认的毫秒数
+	Updates []MemberUpdate `json:"updates,omitempty"` // 捎带的成员更新
+}
+
+// PingResponse 是对探测消息的确认
+type PingResponse struct {
+	From    MemberUpdate   `json:"from"`              // 确认方自己的状态
+	Acked   bool           `json:"acked"`             // 直接探测总是为 true，间接探测时表示目标是否确认
+	Updates []MemberUpdate `json:"updates,omitempty"` // 捎带的成员更新
+}
+
+// Members 返回本节点以及按地址排序的其他成员
+func (g *GossipEngine) Members() (Member, []Member) {
+	return g.members.list()
+}
+
+// OnMemberChange 注册成员状态变化的回调，成员加入、被怀疑、死亡或恢复时调用
+func (g *GossipEngine) OnMemberChange(listener func(Member)) {
+	g.members.subscribe(listener)
+}
+
+// StartFailureDetector 按 SWIM 协议定期探测成员：每个周期直接探测一个成员，
+// 在 timeout 内没有确认时请求 indirectChecks 个成员间接探测，仍然没有确认时怀疑该成员，
+// 怀疑超过 suspicionTimeout 没有被反驳的成员被判定为死亡，死亡或离开超过 reapTimeout 的成员从成员列表中移除
+func (g *GossipEngine) StartFailureDetector(interval, timeout, suspicionTimeout, reapTimeout time.Duration, indirectChecks int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if g.members.left() {
+			continue
+		}
+		g.members.notify(g.members.expireSuspects(now, suspicionTimeout))
+		g.members.reap(now, reapTimeout)
+		g.probe(timeout, indirectChecks)
+	}
+}
+
+// probe 执行一次探测，直接和间接探测都没有确认时怀疑目标
+func (g *GossipEngine) probe(timeout time.Duration, indirectChecks int) {
+	target, ok := g.members.nextProbe()
+	if !ok {
+		return
+	}
+	if _, err := g.ping(target, "", timeout); err == nil {
+		return
+	}
+
+	helpers := g.members.helpers(target, indirectChecks)
+	acks := make(chan bool, len(helpers))
+	for _, helper := range helpers {
+		go func() {
+			response, err := g.ping(helper, target, timeout)
+			acks <- err == nil && response.Acked
+		}()
+	}
+	for range helpers {
+		if <-acks {
+			return
+		}
+	}
+
+	fmt.Printf("Node %s did not respond to probes\n", target)
+	g.members.notify(g.members.suspect(target))
+}
+
+// ping 向成员发送探测消息，target 不为空时请求它在 timeout 内代为探测 target
+// 消息和确认上都捎带成员更新
+func (g *GossipEngine) ping(address, target string, timeout time.Duration) (PingResponse, error) {
+	request := PingRequest{From: g.members.local(), Updates: g.members.piggyback()}
+	path, wait := "/gossip/ping", timeout
+	if target != "" {
+		// 代为探测需要额外的时间
+		request.Target, request.Timeout = target, timeout.Milliseconds()
+		path, wait = "/gossip/ping-req", 2*timeout
+	}
+
+	var response PingResponse
+	if err := postGossipTimeout(address, path, request, &response, wait); err != nil {
+		return response, err
+	}
+	g.receive(append(response.Updates, response.From))
+	return response, nil
+}
+
+// receive 合并收到的成员更新并通知状态变化
+func (g *GossipEngine) receive(updates []MemberUpdate) {
+	g.members.notify(g.members.apply(updates...))
+}
+
+// handlePing 确认对方的探测，ping-req 时先代为探测目标再返回结果
+func (g *GossipEngine) handlePing(c *gin.Context) {
+	var request PingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ping request"})
+		return
+	}
+	g.receive(append(request.Updates, request.From))
+
+	response := PingResponse{Acked: true}
+	if request.Target != "" {
+		_, err := g.ping(request.Target, "", time.Duration(request.Timeout)*time.Millisecond)
+		response.Acked = err == nil
+	}
+	response.From = g.members.local()
+	response.Updates = g.members.piggyback()
+	c.JSON(http.StatusOK, response)
+}
+
+// onMemberChange 根据成员状态的变化调整复制：死亡或离开的成员不再积压变更，新加入或恢复的成员通过反熵同步追上数据
+func (g *GossipEngine) onMemberChange(member Member) {
+	fmt.Printf("Member %s (%s) is %s\n", member.Address, member.NodeID, member.State)
+
+	switch member.State {
+	case MemberDead, MemberLeft:
+		g.mutex.Lock()
+		delete(g.pending, member.Address)
+		g.mutex.Unlock()
+	case MemberAlive:
+		if g.local() != nil && !g.members.left() {
+			go func() {
+				if err := g.syncWith(member.Address); err != nil {
+					fmt.Printf("Failed to synchronize with node %s: %v\n", member.Address, err)
+				}
+			}()
+		}
+	}
+}
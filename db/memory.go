@@ -0,0 +1,153 @@
+package db
+
+import "unsafe"
+
+// 内存估算使用的固定开销，单位为字节
+const (
+	itemOverhead    = int64(unsafe.Sizeof(Item{}))    // 数据项结构体本身
+	siblingOverhead = int64(unsafe.Sizeof(Sibling{})) // 兄弟值结构体本身
+	stringOverhead  = int64(unsafe.Sizeof(""))        // 字符串头部
+	mapOverhead     = 48                              // map 头部及桶的摊销开销
+	indexOverhead   = 128                             // 键在B+树、淘汰策略和过期集合中的槽位及节点的摊销开销，按经验值估计
+)
+
+// Stats 是数据库的容量与淘汰统计
+type Stats struct {
+	Keys         int    `json:"keys"`          // 当前键的数量
+	ExpiringKeys int    `json:"expiring_keys"` // 设置了过期时间的键的数量
+	UsedMemory   int64  `json:"used_memory"`   // 估算的内存占用，单位为字节
+	MaxMemory    int64  `json:"max_memory"`    // 内存上限，0 表示不限制
+	MaxKeys      int    `json:"max_keys"`      // 最大存储条数，0 表示不限制
+	Policy       string `json:"policy"`        // 淘汰策略
+	EvictedKeys  uint64 `json:"evicted_keys"`  // 累计被淘汰的键数量
+	ExpiredKeys  uint64 `json:"expired_keys"`  // 累计因过期被删除的键数量
+}
+
+// estimateSize 估算一个键值对占用的内存
+func estimateSize(key string, value Value) int64 {
+	return stringOverhead + int64(len(key)) + sizeOfValue(value) + itemOverhead + indexOverhead
+}
+
+// sizeOfValue 估算值在 Value 结构体之外占用的内存，结构体本身已计入 itemOverhead
+func sizeOfValue(value Value) int64 {
+	switch value.kind {
+	case TypeString:
+		return int64(len(value.str))
+	case TypeBytes:
+		return int64(cap(value.bytes))
+	case TypeList:
+		var size int64
+		for _, element := range value.list {
+			size += listElementSize(element)
+		}
+		return size
+	case TypeHash:
+		size := int64(mapOverhead)
+		for field, element := range value.hash {
+			size += hashFieldSize(field, element)
+		}
+		return size
+	case TypeSet:
+		size := int64(mapOverhead)
+		for member := range value.set {
+			size += setMemberSize(member)
+		}
+		return size
+	case TypeZSet:
+		size := int64(mapOverhead)
+		for member := range value.zset.scores {
+			size += zsetMemberSize(member)
+		}
+		return size
+	default:
+		return 0
+	}
+}
+
+// replicaSize 估算数据项的版本向量和兄弟值占用的内存，按 siblings 解决冲突时一个键最多保留 maxSiblings 份值
+func replicaSize(clock VersionVector, siblings []Sibling) int64 {
+	var size int64
+	if clock != nil {
+		size += mapOverhead
+		for node := range clock {
+			size += stringOverhead + int64(len(node)) + 8
+		}
+	}
+	for _, sibling := range siblings {
+		size += siblingOverhead + int64(len(sibling.Stamp.Node)) + sizeOfValue(sibling.Value)
+	}
+	return size
+}
+
+// hashFieldSize 估算哈希中一个字段占用的内存
+func hashFieldSize(field, value string) int64 {
+	return 2*stringOverhead + int64(len(field)+len(value))
+}
+
+// setMemberSize 估算集合中一个成员占用的内存
+func setMemberSize(member string) int64 {
+	return stringOverhead + int64(len(member))
+}
+
+// zsetMemberSize 估算有序集合中一个成员占用的内存
+// 成员同时出现在分数表和排序索引中，两处共享字符串内容，各自保存字符串头部和分数
+func zsetMemberSize(member string) int64 {
+	return 2*stringOverhead + int64(len(member)) + 16
+}
+
+// withinLimits 判断再增加 newKeys 个键和 delta 字节后是否仍在容量上限以内，调用方必须持有锁
+func (db *EchoDB) withinLimits(newKeys int, delta int64) bool {
+	if db.maxKeys > 0 && db.data.Len()+newKeys > db.maxKeys {
+		return false
+	}
+	if db.maxMemory > 0 && db.usedMemory+delta > db.maxMemory {
+		return false
+	}
+	return true
+}
+
+// ensureCapacity 在把 key 写为占用 size 字节的数据项之前淘汰数据，保证写入后不超过容量上限
+// 淘汰可能移除 key 本身，因此每一轮都重新计算写入带来的增量。
+// 淘汰策略无法腾出足够空间时返回 ErrOutOfMemory，调用方必须持有写锁
+func (db *EchoDB) ensureCapacity(key string, size int64) error {
+	// 单个数据项就超过内存上限时直接拒绝，避免白白淘汰所有数据
+	if db.maxMemory > 0 && size > db.maxMemory {
+		return ErrOutOfMemory
+	}
+
+	for {
+		newKeys, delta := 1, size
+		if item, exists := db.data.Get(key); exists {
+			newKeys, delta = 0, size-item.size
+		}
+
+		if db.withinLimits(newKeys, delta) {
+			return nil
+		}
+		if !db.evictData() {
+			return ErrOutOfMemory
+		}
+	}
+}
+
+// Stats 返回当前的容量与淘汰统计
+func (db *EchoDB) Stats() Stats {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	policy := db.config.Eviction.Policy
+	if policy == "" {
+		policy = PolicyLFU
+	}
+
+	return Stats{
+		Keys:         db.data.Len(),
+		ExpiringKeys: db.expires.Len(),
+		UsedMemory:   db.usedMemory,
+		MaxMemory:    db.maxMemory,
+		MaxKeys:      db.maxKeys,
+		Policy:       policy,
+		EvictedKeys:  db.evictedKeys,
+		ExpiredKeys:  db.expiredKeys,
+	}
+}
@@ -0,0 +1,395 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// tombstonePurgeInterval 是清理过期删除标记的周期
+const tombstonePurgeInterval = time.Minute
+
+// maxSiblings 是一个键最多保留的兄弟值数量，超出时丢弃时间戳最小的
+const maxSiblings = 16
+
+// Resolution 决定两个节点并发修改同一个键，即两次写入的版本向量互不包含时如何合并
+type Resolution string
+
+const (
+	ResolveLWW      Resolution = "lww"      // 只保留 HLC 时间戳较大的写入
+	ResolveSiblings Resolution = "siblings" // 时间戳较大的写入作为当前值，其余并发写入作为兄弟值保留，由客户端读取后重新写入来解决
+)
+
+// parseResolution 解析配置的冲突解决方式，为空时使用 lww
+func parseResolution(name string) (Resolution, error) {
+	switch Resolution(name) {
+	case ResolveLWW, "":
+		return ResolveLWW, nil
+	case ResolveSiblings:
+		return ResolveSiblings, nil
+	default:
+		return "", fmt.Errorf("unknown conflict resolution %q", name)
+	}
+}
+
+// Sibling 是与当前值并发写入、尚未被客户端解决的另一个值
+type Sibling struct {
+	Value Value `json:"value"`
+	Stamp Stamp `json:"stamp"`
+}
+
+// tombstone 是被删除的键的删除标记
+type tombstone struct {
+	Stamp Stamp
+	Clock VersionVector
+}
+
+// eviction 记录被本节点淘汰的键在淘汰时的版本
+// 淘汰只发生在本地，不会复制给其他节点，其他节点上同一版本的数据不能通过反熵同步再被拉回来，
+// 否则内存上限会被同步抵消。比淘汰时的版本更新的写入仍然会被应用
+type eviction struct {
+	tombstone
+	Siblings []Stamp   // 兄弟值的时间戳，区间摘要中被淘汰的键与其他节点上的同一版本一致
+	At       time.Time // 淘汰的时间，超过删除标记的保留时间后不再阻止拉回
+}
+
+// Mutation 是在节点间复制的一次写入，携带写入后键的完整状态，重复应用是幂等的
+type Mutation struct {
+	Key      string        `json:"key"`
+	Value    *Value        `json:"value,omitempty"`     // 写入后的值，nil 表示键被删除
+	ExpireAt int64         `json:"expire_at,omitempty"` // Unix 纳秒时间戳，0 表示永不过期
+	Stamp    Stamp         `json:"stamp"`
+	Clock    VersionVector `json:"clock,omitempty"`    // 写入后键的版本向量
+	Siblings []Sibling     `json:"siblings,omitempty"` // 未解决的并发写入
+}
+
+// merge 合并本地的状态 local 与复制来的状态 remote，返回合并的结果以及本地状态是否需要更新
+// 一方的版本向量包含另一方时保留较新的一方；并发时时间戳较大的写入胜出，版本向量取两者的合并，
+// 按 siblings 解决时落败的值作为兄弟值保留。任意顺序合并同一组状态都得到相同的结果
+func (resolution Resolution) merge(local, remote Mutation) (Mutation, bool) {
+	switch local.Clock.compare(remote.Clock) {
+	case clockBefore:
+		return remote, true
+	case clockAfter:
+		return local, false
+	case clockEqual:
+		// 没有版本向量的旧数据只能按时间戳比较
+		if remote.Stamp.After(local.Stamp) {
+			return remote, true
+		}
+		return local, false
+	}
+
+	winner, loser := local, remote
+	if remote.Stamp.After(local.Stamp) {
+		winner, loser = remote, local
+	}
+	merged := winner
+	merged.Clock = local.Clock.merge(remote.Clock)
+	merged.Siblings = nil
+	if resolution == ResolveSiblings && winner.Value != nil {
+		merged.Siblings = mergeSiblings(winner, loser)
+	}
+	return merged, true
+}
+
+// mergeSiblings 收集落败一方的值以及双方已有的兄弟值，按时间戳从新到旧排列并去重
+func mergeSiblings(winner, loser Mutation) []Sibling {
+	candidates := append([]Sibling(nil), winner.Siblings...)
+	candidates = append(candidates, loser.Siblings...)
+	if loser.Value != nil {
+		candidates = append(candidates, Sibling{Value: *loser.Value, Stamp: loser.Stamp})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Stamp.After(candidates[j].Stamp)
+	})
+
+	var siblings []Sibling
+	for _, sibling := range candidates {
+		if sibling.Stamp == winner.Stamp || (len(siblings) > 0 && siblings[len(siblings)-1].Stamp == sibling.Stamp) {
+			continue
+		}
+		siblings = append(siblings, sibling)
+		if len(siblings) == maxSiblings {
+			break
+		}
+	}
+	return siblings
+}
+
+// ApplyMutation 合并其他节点复制来的写入，返回本地的数据是否因此被更新
+// 较旧或重复的写入会被忽略，被删除的键留下删除标记，防止较旧的写入使其复活
+func (db *EchoDB) ApplyMutation(mutation Mutation) (bool, error) {
+	if mutation.Value != nil {
+		if err := mutation.Value.validate(); err != nil {
+			return false, err
+		}
+	}
+	for _, sibling := range mutation.Siblings {
+		if err := sibling.Value.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	now := time.Now()
+	db.clock.observe(mutation.Stamp)
+	if evicted, exists := db.evictionOf(mutation.Key); exists && !supersedes(mutation, evicted.tombstone) {
+		return false, nil
+	}
+	merged, changed := db.resolution.merge(db.stateOf(mutation.Key, now), mutation)
+	if !changed {
+		return false, nil
+	}
+	return true, db.applyState(merged, now)
+}
+
+// applyState 把键替换为合并后的状态并记录到 AOF，调用方必须持有写锁
+func (db *EchoDB) applyState(state Mutation, now time.Time) error {
+	key, stamp := state.Key, state.Stamp
+	expiration := decodeExpiration(state.ExpireAt)
+	db.forgetEviction(key)
+	if state.Value == nil || (!expiration.IsZero() && !now.Before(expiration)) {
+		db.removeKey(key)
+		db.tombstones[key] = tombstone{Stamp: stamp, Clock: state.Clock}
+		return db.writeLog(aofRecord{Op: aofDel, Key: key, Stamp: &stamp, Clock: state.Clock})
+	}
+
+	// set 存储的是值的副本，不会与等待转发的变更共享会被原地修改的数据
+	value := *state.Value
+	if err := db.set(key, value, expiration, now); err != nil {
+		return err
+	}
+	if item, exists := db.data.Get(key); exists {
+		db.setReplica(key, item, stamp, state.Clock, state.Siblings)
+	}
+	delete(db.tombstones, key)
+	err := db.writeLog(aofRecord{
+		Op:       aofSet,
+		Key:      key,
+		Value:    &value,
+		ExpireAt: state.ExpireAt,
+		Stamp:    &stamp,
+		Clock:    state.Clock,
+		Siblings: state.Siblings,
+	})
+
+	// 兄弟值和版本向量在写入值之后才计入内存占用，超出容量上限时按淘汰策略腾出空间
+	for !db.withinLimits(0, 0) && db.evictData() {
+	}
+	return err
+}
+
+// writable 检查本节点能否接受本地写入，离开集群后返回 ErrNodeLeft，调用方必须持有写锁
+func (db *EchoDB) writable() error {
+	if db.Gossip != nil && db.Gossip.members.left() {
+		return ErrNodeLeft
+	}
+	return nil
+}
+
+// exclusive 持有写锁执行 fn
+func (db *EchoDB) exclusive(fn func()) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	fn()
+}
+
+// replicate 为刚被本地写操作修改的键生成新的时间戳，开启复制时递增版本向量并把键加入发送队列
+// 键已不存在时按删除处理。调用方必须持有写锁
+func (db *EchoDB) replicate(key string, now time.Time) (Stamp, VersionVector) {
+	stamp := db.clock.now(now, db.nodeID)
+	item, exists := db.data.Get(key)
+	if db.Gossip == nil {
+		if exists {
+			item.Stamp = stamp
+		}
+		return stamp, nil
+	}
+
+	// 键被删除后重新创建时，新的写入接在删除之后
+	previous := db.tombstones[key].Clock
+	if exists && item.Clock != nil {
+		previous = item.Clock
+	}
+	clock := previous.increment(db.nodeID)
+
+	if exists {
+		// 本地写入基于当前值，因果上在所有兄弟值之后，写入即解决了冲突
+		db.setReplica(key, item, stamp, clock, nil)
+		delete(db.tombstones, key)
+		db.forgetEviction(key)
+	} else {
+		db.tombstones[key] = tombstone{Stamp: stamp, Clock: clock}
+	}
+
+	db.Gossip.Broadcast(key)
+	return stamp, clock
+}
+
+// dropKey 删除被本地写操作删除的键，开启复制时先把它的版本向量记入删除标记，使删除在因果上位于之前的写入之后
+// 调用方必须持有写锁
+func (db *EchoDB) dropKey(key string) {
+	if item, exists := db.data.Get(key); exists && db.tombstones != nil {
+		db.tombstones[key] = tombstone{Stamp: item.Stamp, Clock: item.Clock}
+	}
+	db.removeKey(key)
+}
+
+// setReplica 更新数据项的时间戳、版本向量和兄弟值，把它们大小的变化计入内存占用，并重新计算摘要中该键的哈希
+// 调用方必须持有写锁
+func (db *EchoDB) setReplica(key string, item *Item, stamp Stamp, clock VersionVector, siblings []Sibling) {
+	delta := replicaSize(clock, siblings) - replicaSize(item.Clock, item.Siblings)
+	item.Stamp, item.Clock, item.Siblings = stamp, clock, siblings
+	item.size += delta
+	db.usedMemory += delta
+	item.digest = digestEntry(key, stamp, clock, siblingStamps(siblings))
+}
+
+// recordEviction 在淘汰键之前记录它的版本，调用方必须持有写锁
+func (db *EchoDB) recordEviction(key string, now time.Time) {
+	if item, exists := db.data.Get(key); exists && db.evicted != nil {
+		db.evicted.Put(key, eviction{
+			tombstone: tombstone{Stamp: item.Stamp, Clock: item.Clock},
+			Siblings:  siblingStamps(item.Siblings),
+			At:        now,
+		})
+	}
+}
+
+// evictionOf 返回键被本节点淘汰时的版本，调用方必须持有锁
+func (db *EchoDB) evictionOf(key string) (eviction, bool) {
+	if db.evicted == nil {
+		return eviction{}, false
+	}
+	return db.evicted.Get(key)
+}
+
+// forgetEviction 在键被重新写入后删除它的淘汰记录，调用方必须持有写锁
+func (db *EchoDB) forgetEviction(key string) {
+	if db.evicted != nil {
+		db.evicted.Delete(key)
+	}
+}
+
+// supersedes 判断复制来的写入是否比被淘汰的版本更新
+func supersedes(mutation Mutation, evicted tombstone) bool {
+	switch evicted.Clock.compare(mutation.Clock) {
+	case clockBefore:
+		return true
+	case clockAfter:
+		return false
+	default:
+		return mutation.Stamp.After(evicted.Stamp)
+	}
+}
+
+// currentMutations 导出键当前的状态用于复制，已删除的键导出删除标记，既不存在也没有删除标记的键被跳过
+func (db *EchoDB) currentMutations(keys []string) []Mutation {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	now := time.Now()
+	mutations := make([]Mutation, 0, len(keys))
+	for _, key := range keys {
+		if item, exists := db.data.Get(key); exists && !item.expired(now) {
+			mutations = append(mutations, mutationOf(key, item))
+		} else if tombstone, exists := db.tombstones[key]; exists {
+			mutations = append(mutations, Mutation{Key: key, Stamp: tombstone.Stamp, Clock: tombstone.Clock})
+		}
+	}
+	return mutations
+}
+
+// stateOf 返回键在本地的状态，已删除的键返回删除标记，从未写入过的键返回零值
+// 调用方必须持有写锁
+func (db *EchoDB) stateOf(key string, now time.Time) Mutation {
+	if item, exists := db.lookup(key, now); exists {
+		return mutationOf(key, item)
+	}
+	if tombstone, exists := db.tombstones[key]; exists {
+		return Mutation{Key: key, Stamp: tombstone.Stamp, Clock: tombstone.Clock}
+	}
+	return Mutation{Key: key}
+}
+
+// mutationOf 把数据项转换为可以复制给其他节点的状态，其中的值被深拷贝
+func mutationOf(key string, item *Item) Mutation {
+	value := item.Value.Clone()
+	return Mutation{
+		Key:      key,
+		Value:    &value,
+		ExpireAt: encodeExpiration(item.Expiration),
+		Stamp:    item.Stamp,
+		Clock:    item.Clock,
+		Siblings: item.Siblings,
+	}
+}
+
+// restoreReplica 恢复持久化文件中记录的时间戳、版本向量和兄弟值，调用方必须持有写锁
+func (db *EchoDB) restoreReplica(record aofRecord) {
+	if record.Stamp == nil {
+		return
+	}
+	db.clock.observe(*record.Stamp)
+	if item, exists := db.data.Get(record.Key); exists {
+		db.setReplica(record.Key, item, *record.Stamp, record.Clock, record.Siblings)
+	} else if db.tombstones != nil {
+		db.tombstones[record.Key] = tombstone{Stamp: *record.Stamp, Clock: record.Clock}
+	}
+}
+
+// purgeTombstones 删除早于 ttl 的删除标记，以及淘汰时间早于 ttl 的淘汰记录
+// 删除标记只需要保留到删除操作传播到所有节点为止；淘汰记录过期后，其他节点上的数据可能被反熵同步拉回，
+// 超出内存上限时会再次被淘汰
+func (db *EchoDB) purgeTombstones(now time.Time, ttl time.Duration) int {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	deadline := now.Add(-ttl)
+	purged := 0
+	for key, tombstone := range db.tombstones {
+		if tombstone.Stamp.Time < deadline.UnixNano() {
+			delete(db.tombstones, key)
+			purged++
+		}
+	}
+	var expired []string
+	db.evicted.Scan(func(key string, evicted eviction) bool {
+		if evicted.At.Before(deadline) {
+			expired = append(expired, key)
+		}
+		return true
+	})
+	for _, key := range expired {
+		db.evicted.Delete(key)
+	}
+	return purged
+}
+
+// startTombstoneProcess 定期清理过期的删除标记
+func (db *EchoDB) startTombstoneProcess(ttl time.Duration) {
+	ticker := time.NewTicker(tombstonePurgeInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		db.purgeTombstones(now, ttl)
+	}
+}
+
+// handleMutations 合并一批其他节点复制来的变更，并把合并后的状态继续转发给其他节点
+func (db *EchoDB) handleMutations(mutations []Mutation) {
+	for _, mutation := range mutations {
+		applied, err := db.ApplyMutation(mutation)
+		if err != nil {
+			fmt.Printf("Failed to apply mutation of key %s: %v\n", mutation.Key, err)
+			continue
+		}
+		if applied {
+			db.Gossip.Broadcast(mutation.Key)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor 表示分页游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPrefix 保证任何键（包括空键）编码后的游标都不为空
+const cursorPrefix = "k"
+
+// ScanResult 是分页扫描的结果
+type ScanResult struct {
+	Entries    []Entry `json:"entries"`
+	NextCursor string  `json:"next_cursor,omitempty"` // 下一页的游标，为空表示没有更多数据
+}
+
+// scanOptions 描述一次扫描的边界和方向
+type scanOptions struct {
+	start   string // 下界（包含）
+	end     string // 上界（包含）
+	hasEnd  bool   // 是否设置了上界
+	prefix  string // 只返回带有该前缀的键
+	reverse bool   // 是否按键的降序扫描
+	limit   int    // 最多返回的条目数，不为正数时不限制
+}
+
+// encodeCursor 把下一页起始的键编码为不透明的游标
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + key))
+}
+
+// decodeCursor 解析游标得到下一页起始的键
+func decodeCursor(cursor string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return "", ErrInvalidCursor
+	}
+	return strings.TrimPrefix(string(raw), cursorPrefix), nil
+}
+
+// Range 按键的升序返回位于 [start, end] 区间内的数据项，end 为空时不设上界
+// cursor 为上一页返回的 NextCursor，首次查询时传空字符串
+func (db *EchoDB) Range(start, end, cursor string, limit int) (ScanResult, error) {
+	return db.scan(scanOptions{start: start, end: end, hasEnd: end != "", limit: limit}, cursor)
+}
+
+// ReverseRange 按键的降序返回位于 [start, end] 区间内的数据项，end 为空时从最大的键开始
+func (db *EchoDB) ReverseRange(start, end, cursor string, limit int) (ScanResult, error) {
+	return db.scan(scanOptions{start: start, end: end, hasEnd: end != "", reverse: true, limit: limit}, cursor)
+}
+
+// Prefix 按键的升序返回带有指定前缀的数据项
+func (db *EchoDB) Prefix(prefix, cursor string, limit int) (ScanResult, error) {
+	return db.scan(scanOptions{start: prefix, prefix: prefix, limit: limit}, cursor)
+}
+
+// ReversePrefix 按键的降序返回带有指定前缀的数据项
+func (db *EchoDB) ReversePrefix(prefix, cursor string, limit int) (ScanResult, error) {
+	return db.scan(scanOptions{start: prefix, prefix: prefix, reverse: true, limit: limit}, cursor)
+}
+
+// prefixEnd 返回大于所有带有该前缀的键的最小字符串，前缀为空或全部由 0xff 组成时不存在这样的字符串
+func prefixEnd(prefix string) (string, bool) {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			return prefix[:i] + string([]byte{prefix[i] + 1}), true
+		}
+	}
+	return "", false
+}
+
+// scan 在B+树上定位起点后沿叶子链表移动，跳过已过期的数据项
+func (db *EchoDB) scan(opts scanOptions, cursor string) (ScanResult, error) {
+	// 游标记录的是下一页的第一个键，用它收紧扫描的起点
+	if cursor != "" {
+		key, err := decodeCursor(cursor)
+		if err != nil {
+			return ScanResult{}, err
+		}
+		if opts.reverse {
+			if !opts.hasEnd || key < opts.end {
+				opts.end, opts.hasEnd = key, true
+			}
+		} else if key > opts.start {
+			opts.start = key
+		}
+	}
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	var it *Iterator[string, *Item]
+	if !opts.reverse {
+		it = db.data.Seek(opts.start)
+	} else if !opts.hasEnd {
+		it = db.data.SeekLast()
+		// 按前缀降序扫描时从最后一个带有该前缀的键开始
+		if end, ok := prefixEnd(opts.prefix); ok {
+			it = db.data.Seek(end)
+			it.Prev()
+		}
+	} else {
+		// 定位到最后一个不大于上界的键
+		it = db.data.Seek(opts.end)
+		if !it.Valid() || it.Key() > opts.end {
+			it.Prev()
+		}
+	}
+
+	now := time.Now()
+	result := ScanResult{Entries: []Entry{}}
+	for ; it.Valid(); opts.advance(it) {
+		key := it.Key()
+		if !opts.inRange(key) {
+			break
+		}
+
+		item := it.Value()
+		if item.expired(now) {
+			continue
+		}
+
+		// 已经取满一页，当前键作为下一页的起点
+		if opts.limit > 0 && len(result.Entries) == opts.limit {
+			result.NextCursor = encodeCursor(key)
+			break
+		}
+		result.Entries = append(result.Entries, Entry{Key: key, Item: *item.clone()})
+	}
+
+	return result, nil
+}
+
+// advance 按扫描方向移动游标
+func (opts scanOptions) advance(it *Iterator[string, *Item]) {
+	if opts.reverse {
+		it.Prev()
+	} else {
+		it.Next()
+	}
+}
+
+// inRange 判断键是否仍在扫描范围内
+func (opts scanOptions) inRange(key string) bool {
+	if opts.prefix != "" && !strings.HasPrefix(key, opts.prefix) {
+		return false
+	}
+	return key >= opts.start && (!opts.hasEnd || key <= opts.end)
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// SAdd 向集合添加成员，返回新增成员的数量
+// 键不存在时创建一个永不过期的集合，已有键保留原来的过期时间
+func (db *EchoDB) SAdd(key string, members ...string) (int, error) {
+	if len(members) == 0 {
+		return 0, fmt.Errorf("%w: at least one member is required", ErrInvalidValue)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	added, err := db.sadd(key, members, time.Now())
+	if err != nil || added == 0 {
+		return added, err
+	}
+	return added, db.appendLog(aofRecord{Op: aofSAdd, Key: key, Args: members})
+}
+
+// SRem 从集合移除成员，返回实际移除的数量，最后一个成员被移除后键也会被删除
+func (db *EchoDB) SRem(key string, members ...string) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	removed, err := db.srem(key, members, time.Now())
+	if err != nil || removed == 0 {
+		return removed, err
+	}
+	return removed, db.appendLog(aofRecord{Op: aofSRem, Key: key, Args: members})
+}
+
+// SMembers 返回按字典序排列的集合成员，键不存在时返回空切片
+func (db *EchoDB) SMembers(key string) ([]string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.readCollection(key, TypeSet, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return []string{}, nil
+	}
+	return item.Value.AsSet()
+}
+
+// SInter 返回所有集合的交集，按字典序排列
+// 任意一个键不存在时交集为空，任意一个键不是集合时返回 ErrWrongType
+func (db *EchoDB) SInter(keys ...string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: at least one key is required", ErrInvalidValue)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	now := time.Now()
+	sets := make([]map[string]struct{}, 0, len(keys))
+	empty := false
+	for _, key := range keys {
+		item, err := db.readCollection(key, TypeSet, now)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			empty = true
+			continue
+		}
+		sets = append(sets, item.Value.set)
+	}
+	if empty {
+		return []string{}, nil
+	}
+
+	// 从最小的集合出发检查其余集合
+	slices.SortFunc(sets, func(a, b map[string]struct{}) int { return len(a) - len(b) })
+	result := []string{}
+	for member := range sets[0] {
+		found := true
+		for _, other := range sets[1:] {
+			if _, exists := other[member]; !exists {
+				found = false
+				break
+			}
+		}
+		if found {
+			result = append(result, member)
+		}
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+// sadd 是 SAdd 的实现，调用方必须持有写锁
+func (db *EchoDB) sadd(key string, members []string, now time.Time) (int, error) {
+	// 去重后计算新增的成员
+	unique := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		unique[member] = struct{}{}
+	}
+	growth := func(value Value) int64 {
+		var delta int64
+		for member := range unique {
+			if _, exists := value.set[member]; !exists {
+				delta += setMemberSize(member)
+			}
+		}
+		return delta
+	}
+
+	item, err := db.growCollection(key, TypeSet, now, growth)
+	if err != nil {
+		return 0, err
+	}
+
+	delta := growth(item.Value)
+	before := len(item.Value.set)
+	maps.Copy(item.Value.set, unique)
+	added := len(item.Value.set) - before
+	db.resize(key, item, delta, added > 0)
+	return added, nil
+}
+
+// srem 是 SRem 的实现，调用方必须持有写锁
+func (db *EchoDB) srem(key string, members []string, now time.Time) (int, error) {
+	item, err := db.readCollection(key, TypeSet, now)
+	if err != nil || item == nil {
+		return 0, err
+	}
+
+	var delta int64
+	removed := 0
+	for _, member := range members {
+		if _, exists := item.Value.set[member]; exists {
+			delete(item.Value.set, member)
+			delta -= setMemberSize(member)
+			removed++
+		}
+	}
+	if removed > 0 {
+		db.resize(key, item, delta, true)
+	}
+	return removed, nil
+}
@@ -0,0 +1,443 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrCorruptSnapshot 表示快照文件不完整或校验失败
+var ErrCorruptSnapshot = errors.New("snapshot file is corrupted")
+
+// ErrSaveInProgress 表示已有一个快照正在保存
+var ErrSaveInProgress = errors.New("background save already in progress")
+
+const (
+	snapshotMagic   = "ECHOSNAP" // 快照文件头
+	snapshotVersion = 2          // 当前写入的格式版本
+	snapshotPrefix  = "snapshot-"
+	snapshotSuffix  = ".rdb"
+
+	snapshotMaxEntrySize = 512 << 20 // 单个条目的长度上限，超过时视为损坏
+)
+
+// 快照文件格式（所有整数均为大端序）：
+//
+//	magic    8 字节 "ECHOSNAP"
+//	version  uint32 格式版本
+//	body     由版本决定的内容
+//	checksum uint32 之前所有字节的 CRC32-C
+//
+// 版本 2 的 body：
+//
+//	created  int64  保存时刻的 Unix 纳秒时间戳
+//	count    uint64 条目数量
+//	entries  count 个条目，每个条目为 uint32 长度加 Entry 的 JSON 编码
+//
+// 版本 1 的 body 结构相同，只是条目中的值没有类型标签。
+// 新版本只追加读取函数，旧版本的快照在升级后仍然可以加载
+
+// Snapshot 是从快照文件中读出的数据
+type Snapshot struct {
+	Created time.Time
+	Entries []Entry
+}
+
+// SnapshotStatus 是快照保存的状态
+type SnapshotStatus struct {
+	InProgress   bool      `json:"in_progress"`          // 是否正在保存
+	LastSaveTime time.Time `json:"last_save_time"`       // 最近一次成功保存的时间
+	LastFile     string    `json:"last_file,omitempty"`  // 最近一次成功保存的文件
+	LastKeys     int       `json:"last_keys"`            // 最近一次成功保存的键数量
+	LastError    string    `json:"last_error,omitempty"` // 最近一次保存失败的原因，成功后清空
+}
+
+// snapshotFileName 返回保存时刻对应的文件名，文件名的字典序与时间顺序一致
+func snapshotFileName(created time.Time) string {
+	return fmt.Sprintf("%s%020d%s", snapshotPrefix, created.UnixNano(), snapshotSuffix)
+}
+
+// listSnapshots 返回目录中的快照文件，从新到旧排列
+func listSnapshots(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, file := range files {
+		name := file.Name()
+		if !file.IsDir() && strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotSuffix) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	slices.Sort(paths)
+	slices.Reverse(paths)
+	return paths, nil
+}
+
+// writeSnapshot 把条目写入 dir 中的新快照文件并返回其路径
+// 先写入临时文件并 fsync，再重命名为正式文件名，崩溃时不会留下写了一半的快照
+func writeSnapshot(dir string, created time.Time, entries []Entry) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, snapshotFileName(created))
+	temp, err := os.CreateTemp(dir, snapshotPrefix+"*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+
+	if err := encodeSnapshot(temp, created, entries); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return "", err
+	}
+
+	// 同步目录，保证重命名本身也已落盘
+	if parent, err := os.Open(dir); err == nil {
+		parent.Sync()
+		parent.Close()
+	}
+	return path, nil
+}
+
+// encodeSnapshot 按当前版本的格式编码快照
+func encodeSnapshot(w io.Writer, created time.Time, entries []Entry) error {
+	checksum := crc32.New(aofChecksumTable)
+	writer := bufio.NewWriter(io.MultiWriter(w, checksum))
+
+	writer.WriteString(snapshotMagic)
+	binary.Write(writer, binary.BigEndian, uint32(snapshotVersion))
+	binary.Write(writer, binary.BigEndian, created.UnixNano())
+	binary.Write(writer, binary.BigEndian, uint64(len(entries)))
+
+	for _, entry := range entries {
+		payload, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("encode snapshot entry %q: %w", entry.Key, err)
+		}
+		binary.Write(writer, binary.BigEndian, uint32(len(payload)))
+		writer.Write(payload)
+	}
+
+	// 校验和覆盖之前的所有字节，必须先把缓冲区刷新到校验和中
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, checksum.Sum32())
+}
+
+// readSnapshot 读取并校验一个快照文件
+// 所有条目都在校验通过后才返回，损坏的快照不会留下部分数据
+func readSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	checksum := crc32.New(aofChecksumTable)
+	buffered := bufio.NewReader(file)
+	reader := io.TeeReader(buffered, checksum)
+
+	header := make([]byte, len(snapshotMagic)+4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptSnapshot, err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: unknown file header", ErrCorruptSnapshot)
+	}
+
+	var snapshot *Snapshot
+	switch version := binary.BigEndian.Uint32(header[len(snapshotMagic):]); version {
+	case 1:
+		snapshot, err = readSnapshotEntries(reader, decodeLegacyEntry)
+	case 2:
+		snapshot, err = readSnapshotEntries(reader, decodeEntry)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptSnapshot, err)
+	}
+
+	if err := verifyChecksum(buffered, checksum); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// readSnapshotEntries 读取版本 1 和 2 共用的 body 结构，条目由 decode 解析
+func readSnapshotEntries(reader io.Reader, decode func([]byte) (Entry, error)) (*Snapshot, error) {
+	var created int64
+	var count uint64
+	if err := binary.Read(reader, binary.BigEndian, &created); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{Created: time.Unix(0, created)}
+	for i := uint64(0); i < count; i++ {
+		var length uint32
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+		if length > snapshotMaxEntrySize {
+			return nil, fmt.Errorf("entry %d is %d bytes", i, length)
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return nil, err
+		}
+
+		entry, err := decode(payload)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i, err)
+		}
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+	return snapshot, nil
+}
+
+// decodeEntry 解析当前版本的条目
+func decodeEntry(payload []byte) (Entry, error) {
+	var entry Entry
+	err := json.Unmarshal(payload, &entry)
+	return entry, err
+}
+
+// decodeLegacyEntry 解析版本 1 的条目，其中的值没有类型标签
+func decodeLegacyEntry(payload []byte) (Entry, error) {
+	var legacy struct {
+		Key          string          `json:"key"`
+		Value        json.RawMessage `json:"value"`
+		Frequency    int             `json:"frequency"`
+		LastAccessed time.Time       `json:"last_accessed"`
+		Expiration   time.Time       `json:"expiration"`
+	}
+	if err := json.Unmarshal(payload, &legacy); err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{Key: legacy.Key, Item: Item{
+		Value:        legacyValue(legacy.Value),
+		Frequency:    legacy.Frequency,
+		LastAccessed: legacy.LastAccessed,
+		Expiration:   legacy.Expiration,
+	}}, nil
+}
+
+// verifyChecksum 读取文件末尾的校验和并与已读内容的校验和比较，校验和之后不允许有多余数据
+func verifyChecksum(reader io.Reader, checksum hash.Hash32) error {
+	var expected uint32
+	if err := binary.Read(reader, binary.BigEndian, &expected); err != nil {
+		return fmt.Errorf("%w: missing checksum: %v", ErrCorruptSnapshot, err)
+	}
+	if expected != checksum.Sum32() {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorruptSnapshot)
+	}
+	if n, _ := io.Copy(io.Discard, reader); n > 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", ErrCorruptSnapshot, n)
+	}
+	return nil
+}
+
+// loadSnapshot 从最新的有效快照恢复数据，损坏或无法识别的快照会被跳过
+// 加载发生在 NewEchoDB 返回之前，此时还没有其他协程访问数据库
+func (db *EchoDB) loadSnapshot() error {
+	paths, err := listSnapshots(db.config.Snapshot.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		snapshot, err := readSnapshot(path)
+		if err != nil {
+			log.Printf("skipping snapshot %s: %v", path, err)
+			continue
+		}
+
+		now := time.Now()
+		for _, entry := range snapshot.Entries {
+			if entry.expired(now) {
+				continue
+			}
+			item := entry.Item
+			if err := db.restore(entry.Key, &item); err != nil {
+				return fmt.Errorf("load snapshot %s: %w", path, err)
+			}
+		}
+
+		db.snapshotStatus.LastSaveTime = snapshot.Created
+		db.snapshotStatus.LastFile = path
+		db.snapshotStatus.LastKeys = len(snapshot.Entries)
+		log.Printf("loaded %d keys from snapshot %s", db.data.Len(), path)
+		return nil
+	}
+	return nil
+}
+
+// restore 写入从持久化文件中恢复的数据项，保留其访问统计和过期时间，调用方必须持有写锁
+func (db *EchoDB) restore(key string, item *Item) error {
+	db.removeKey(key)
+
+	item.size = estimateSize(key, item.Value) + replicaSize(item.Clock, item.Siblings)
+	item.digest = digestEntry(key, item.Stamp, item.Clock, siblingStamps(item.Siblings))
+	if err := db.ensureCapacity(key, item.size); err != nil {
+		return err
+	}
+
+	db.data.Put(key, item)
+	db.usedMemory += item.size
+	db.policy.OnInsert(key, item)
+	db.setExpiration(key, item, item.Expiration)
+
+	db.clock.observe(item.Stamp)
+
+	// 旧版本的快照没有记录版本号，恢复时重新分配
+	if item.Version == 0 {
+		db.bumpVersion(item)
+	} else {
+		db.version = max(db.version, item.Version)
+	}
+	return nil
+}
+
+// Save 同步保存一个快照
+func (db *EchoDB) Save() error {
+	if !db.beginSave() {
+		return ErrSaveInProgress
+	}
+	return db.save()
+}
+
+// BackgroundSave 在后台保存一个快照，保存结果可以通过 SnapshotStatus 查询
+func (db *EchoDB) BackgroundSave() error {
+	if !db.beginSave() {
+		return ErrSaveInProgress
+	}
+	go db.save()
+	return nil
+}
+
+// SnapshotStatus 返回快照保存的状态
+func (db *EchoDB) SnapshotStatus() SnapshotStatus {
+	db.snapshotMutex.Lock()
+	defer db.snapshotMutex.Unlock()
+	return db.snapshotStatus
+}
+
+// beginSave 标记开始保存，已有保存正在进行时返回 false
+func (db *EchoDB) beginSave() bool {
+	db.snapshotMutex.Lock()
+	defer db.snapshotMutex.Unlock()
+
+	if db.snapshotStatus.InProgress {
+		return false
+	}
+	db.snapshotStatus.InProgress = true
+	return true
+}
+
+// save 保存快照并记录结果，调用前必须已通过 beginSave 标记开始
+// 只在复制数据项时持有读锁，编码和写盘都在锁外进行，因此写入只会被短暂阻塞
+func (db *EchoDB) save() error {
+	created, entries := db.snapshotEntries()
+	path, err := writeSnapshot(db.config.Snapshot.Dir, created, entries)
+	if err == nil {
+		db.pruneSnapshots()
+	}
+
+	db.snapshotMutex.Lock()
+	defer db.snapshotMutex.Unlock()
+
+	db.snapshotStatus.InProgress = false
+	if err != nil {
+		db.snapshotStatus.LastError = err.Error()
+		log.Printf("save snapshot: %v", err)
+		return err
+	}
+	db.snapshotStatus.LastSaveTime = created
+	db.snapshotStatus.LastFile = path
+	db.snapshotStatus.LastKeys = len(entries)
+	db.snapshotStatus.LastError = ""
+	return nil
+}
+
+// snapshotEntries 复制当前所有未过期的数据项，得到一个时间点一致的视图
+func (db *EchoDB) snapshotEntries() (time.Time, []Entry) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	now := time.Now()
+	return now, db.copyEntries(now)
+}
+
+// copyEntries 复制在 now 时刻未过期的所有数据项，调用方必须持有锁
+func (db *EchoDB) copyEntries(now time.Time) []Entry {
+	entries := make([]Entry, 0, db.data.Len())
+	db.data.Scan(func(key string, item *Item) bool {
+		if !item.expired(now) {
+			entries = append(entries, Entry{Key: key, Item: *item.clone()})
+		}
+		return true
+	})
+	return entries
+}
+
+// pruneSnapshots 只保留最新的若干个快照
+func (db *EchoDB) pruneSnapshots() {
+	paths, err := listSnapshots(db.config.Snapshot.Dir)
+	if err != nil {
+		log.Printf("list snapshots: %v", err)
+		return
+	}
+
+	retain := max(db.config.Snapshot.Retain, 1)
+	for _, path := range paths[min(retain, len(paths)):] {
+		if err := os.Remove(path); err != nil {
+			log.Printf("remove snapshot: %v", err)
+		}
+	}
+}
+
+// startSnapshotProcess 按配置的间隔定期在后台保存快照
+func (db *EchoDB) startSnapshotProcess(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := db.BackgroundSave(); err != nil && !errors.Is(err, ErrSaveInProgress) {
+			log.Printf("schedule snapshot: %v", err)
+		}
+	}
+}
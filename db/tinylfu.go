@@ -0,0 +1,225 @@
+package db
+
+import (
+	"container/list"
+	"hash/maphash"
+	"math/bits"
+)
+
+// W-TinyLFU 各区域占比，取值参考 Caffeine
+const (
+	tinyLFUWindowPercent    = 1       // 窗口区占全部键的百分比
+	tinyLFUProtectedPercent = 80      // 保护区占主区的百分比
+	sketchDepth             = 4       // Count-Min Sketch 的行数
+	sketchMaxCount          = 15      // 计数器上限，相当于 4 位计数器
+	sketchMinWidth          = 64      // 每行计数器的最小数量
+	sketchDefaultWidth      = 1 << 16 // 未限制键数量时每行计数器的数量
+	sketchResetMultiplier   = 10      // 累计增加 宽度*该倍数 次后将所有计数减半
+)
+
+// countMinSketch 用于估计键的访问频率
+// 每行用不同的哈希定位一个计数器，估计值取各行的最小值；累计一定次数后所有计数减半以实现老化
+type countMinSketch struct {
+	seed      maphash.Seed
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := max(capacity, sketchMinWidth)
+	width = 1 << bits.Len(uint(width-1)) // 向上取整到 2 的幂
+
+	sketch := &countMinSketch{
+		seed:    maphash.MakeSeed(),
+		mask:    uint64(width - 1),
+		resetAt: width * sketchResetMultiplier,
+	}
+	for i := range sketch.rows {
+		sketch.rows[i] = make([]uint8, width)
+	}
+	return sketch
+}
+
+// indexes 通过双重哈希为每一行计算计数器位置
+func (s *countMinSketch) indexes(key string) [sketchDepth]uint64 {
+	hash := maphash.String(s.seed, key)
+	step := hash>>32 | 1
+
+	var result [sketchDepth]uint64
+	for i := range result {
+		result[i] = (hash + uint64(i)*step) & s.mask
+	}
+	return result
+}
+
+// Increment 记录一次访问
+func (s *countMinSketch) Increment(key string) {
+	for i, index := range s.indexes(key) {
+		if s.rows[i][index] < sketchMaxCount {
+			s.rows[i][index]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+// Estimate 返回访问频率的估计值
+func (s *countMinSketch) Estimate(key string) uint8 {
+	estimate := uint8(sketchMaxCount)
+	for i, index := range s.indexes(key) {
+		estimate = min(estimate, s.rows[i][index])
+	}
+	return estimate
+}
+
+// reset 将所有计数减半，使历史访问的影响逐渐衰减
+func (s *countMinSketch) reset() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// 键所在的区域
+const (
+	segmentWindow = iota
+	segmentProbation
+	segmentProtected
+)
+
+// tinyLFUEntry 是 W-TinyLFU 策略中的一个键
+type tinyLFUEntry struct {
+	key     string
+	segment int
+}
+
+// tinyLFUPolicy 是 W-TinyLFU 淘汰策略
+// 新键先进入很小的 LRU 窗口区，溢出后进入主区的试用段；试用段中的键再次被访问时晋升到保护段。
+// 淘汰时让窗口区最久未访问的候选键与试用段的淘汰对象比较 Sketch 估计的访问频率，频率较低者被淘汰，
+// 从而既能吸收突发的新键，又能阻止一次性访问的键挤出真正的热点数据
+type tinyLFUPolicy struct {
+	sketch    *countMinSketch
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	entries   map[string]*list.Element
+}
+
+func newTinyLFUPolicy(capacity int) *tinyLFUPolicy {
+	if capacity <= 0 {
+		capacity = sketchDefaultWidth
+	}
+	return &tinyLFUPolicy{
+		sketch:    newCountMinSketch(capacity),
+		window:    list.New(),
+		probation: list.New(),
+		protected: list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+}
+
+// segmentList 返回区域对应的链表
+func (p *tinyLFUPolicy) segmentList(segment int) *list.List {
+	switch segment {
+	case segmentWindow:
+		return p.window
+	case segmentProbation:
+		return p.probation
+	default:
+		return p.protected
+	}
+}
+
+// move 把键移动到目标区域的最前端
+func (p *tinyLFUPolicy) move(element *list.Element, segment int) {
+	entry := element.Value.(*tinyLFUEntry)
+	p.segmentList(entry.segment).Remove(element)
+	entry.segment = segment
+	p.entries[entry.key] = p.segmentList(segment).PushFront(entry)
+}
+
+// rebalance 让窗口区和保护段保持在各自的容量以内，溢出的键降级到试用段
+func (p *tinyLFUPolicy) rebalance() {
+	total := len(p.entries)
+	windowLimit := max(1, total*tinyLFUWindowPercent/100)
+	protectedLimit := (total - windowLimit) * tinyLFUProtectedPercent / 100
+
+	for p.window.Len() > windowLimit {
+		p.move(p.window.Back(), segmentProbation)
+	}
+	for p.protected.Len() > protectedLimit {
+		p.move(p.protected.Back(), segmentProbation)
+	}
+}
+
+func (p *tinyLFUPolicy) OnInsert(key string, item *Item) {
+	if _, exists := p.entries[key]; exists {
+		p.OnAccess(key, item)
+		return
+	}
+
+	p.sketch.Increment(key)
+	p.entries[key] = p.window.PushFront(&tinyLFUEntry{key: key, segment: segmentWindow})
+	p.rebalance()
+}
+
+func (p *tinyLFUPolicy) OnAccess(key string, item *Item) {
+	element, exists := p.entries[key]
+	if !exists {
+		return
+	}
+
+	p.sketch.Increment(key)
+	switch element.Value.(*tinyLFUEntry).segment {
+	case segmentProbation:
+		// 试用段中的键再次被访问，晋升到保护段
+		p.move(element, segmentProtected)
+		p.rebalance()
+	case segmentWindow:
+		p.window.MoveToFront(element)
+	default:
+		p.protected.MoveToFront(element)
+	}
+}
+
+func (p *tinyLFUPolicy) OnDelete(key string) {
+	element, exists := p.entries[key]
+	if !exists {
+		return
+	}
+
+	p.segmentList(element.Value.(*tinyLFUEntry).segment).Remove(element)
+	delete(p.entries, key)
+}
+
+func (p *tinyLFUPolicy) Victim() (string, bool) {
+	var candidate, victim *list.Element
+	candidate = p.window.Back()
+	if victim = p.probation.Back(); victim == nil {
+		victim = p.protected.Back()
+	}
+
+	switch {
+	case candidate == nil && victim == nil:
+		return "", false
+	case victim == nil:
+		return candidate.Value.(*tinyLFUEntry).key, true
+	case candidate == nil:
+		return victim.Value.(*tinyLFUEntry).key, true
+	}
+
+	// 候选键的估计频率更高时才准入主区，否则淘汰候选键本身
+	candidateKey := candidate.Value.(*tinyLFUEntry).key
+	victimKey := victim.Value.(*tinyLFUEntry).key
+	if p.sketch.Estimate(candidateKey) > p.sketch.Estimate(victimKey) {
+		return victimKey, true
+	}
+	return candidateKey, true
+}
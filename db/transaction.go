@@ -0,0 +1,288 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTxAborted 表示事务监视的键在执行前被修改，事务中的操作都没有执行
+var ErrTxAborted = errors.New("transaction aborted: a watched key was modified")
+
+// ErrTxInvalid 表示事务在排队时出现了错误，整个事务被拒绝执行
+var ErrTxInvalid = errors.New("transaction discarded because of previous errors")
+
+// txCommand 是排队等待执行的操作，执行时调用方持有写锁
+type txCommand func(now time.Time) (interface{}, error)
+
+// TxResult 是事务中一个操作的结果
+// 与 Redis 一致，单个操作执行失败不会回滚事务中的其他操作
+type TxResult struct {
+	Value interface{} // 操作的返回值，与对应的单键方法一致
+	Err   error       // 操作的错误
+}
+
+// Transaction 是一组排队后原子执行的操作，对应 Redis 的 MULTI/EXEC
+// 执行时持有数据库的写锁，其他读写不会看到只执行了一部分的事务，开启 AOF 时事务作为一条记录写入
+// 开启复制时事务修改的键按键分别复制给其他节点，其他节点不保证原子地看到整个事务
+// 通过 Watch 实现乐观并发控制：被监视的键的版本号在执行前发生变化时整个事务被放弃
+// Transaction 不是并发安全的，只能在一个 goroutine 中使用
+type Transaction struct {
+	db       *EchoDB
+	watched  map[string]uint64 // 被监视的键及期望的版本号，0 表示期望键不存在
+	commands []txCommand
+	err      error // 排队时发现的第一个错误
+}
+
+// Multi 开启一个事务
+func (db *EchoDB) Multi() *Transaction {
+	return &Transaction{db: db, watched: make(map[string]uint64)}
+}
+
+// Watch 监视键的当前版本，执行事务时任意一个键被修改、删除或过期都会放弃整个事务
+func (tx *Transaction) Watch(keys ...string) {
+	tx.db.mutex.RLock()
+	defer tx.db.mutex.RUnlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		var version uint64
+		if item, exists := tx.db.data.Get(key); exists && !item.expired(now) {
+			version = item.Version
+		}
+		tx.watched[key] = version
+	}
+}
+
+// WatchVersion 监视键并指定期望的版本号，适用于版本号来自之前读取结果的场景，0 表示期望键不存在
+func (tx *Transaction) WatchVersion(key string, version uint64) {
+	tx.watched[key] = version
+}
+
+// Discard 清空排队的操作和监视的键
+func (tx *Transaction) Discard() {
+	tx.watched = make(map[string]uint64)
+	tx.commands = nil
+	tx.err = nil
+}
+
+// queue 把操作加入队列，err 不为 nil 时记录排队错误
+func (tx *Transaction) queue(err error, command txCommand) {
+	if err != nil {
+		if tx.err == nil {
+			tx.err = fmt.Errorf("command %d: %w", len(tx.commands), err)
+		}
+		command = nil
+	}
+	tx.commands = append(tx.commands, command)
+}
+
+// Exec 原子地执行排队的操作，返回每个操作的结果
+// 被监视的键已被修改时返回 ErrTxAborted，排队时出现错误时返回 ErrTxInvalid，这两种情况下没有任何操作被执行
+// 无论结果如何，执行后事务的队列和监视的键都会被清空
+func (tx *Transaction) Exec() ([]TxResult, error) {
+	defer tx.Discard()
+	if tx.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTxInvalid, tx.err)
+	}
+
+	db := tx.db
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for key, expected := range tx.watched {
+		var version uint64
+		if item, exists := db.lookup(key, now); exists {
+			version = item.Version
+		}
+		if version != expected {
+			return nil, ErrTxAborted
+		}
+	}
+
+	// 收集事务产生的 AOF 记录，执行结束后作为一条记录写入
+	var records []aofRecord
+	db.txLog = &records
+	results := make([]TxResult, len(tx.commands))
+	for i, command := range tx.commands {
+		results[i].Value, results[i].Err = command(now)
+	}
+	db.txLog = nil
+
+	if len(records) == 0 {
+		return results, nil
+	}
+	return results, db.writeLog(aofRecord{Op: aofMulti, Batch: records})
+}
+
+// Get 在事务中查询键的值，结果为 Value
+func (tx *Transaction) Get(key string) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		item, exists := tx.db.lookup(key, now)
+		if !exists {
+			return nil, ErrKeyNotFound
+		}
+		tx.db.touch(key, item, now)
+		return item.Value.Clone(), nil
+	})
+}
+
+// Set 在事务中写入数据，ttl 为 0 表示永不过期，结果为写入后的版本号
+func (tx *Transaction) Set(key string, value Value, ttl time.Duration) {
+	err := value.validate()
+	if ttl < 0 {
+		err = ErrInvalidTTL
+	}
+	tx.queue(err, func(now time.Time) (interface{}, error) {
+		if err := tx.db.store(key, value, expirationFor(now, ttl), now); err != nil {
+			return nil, err
+		}
+		return tx.db.version, nil
+	})
+}
+
+// Delete 在事务中删除键
+func (tx *Transaction) Delete(key string) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		return nil, tx.db.del(key, now)
+	})
+}
+
+// Expire 在事务中为键设置过期时间，ttl 不为正数时删除该键
+func (tx *Transaction) Expire(key string, ttl time.Duration) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		return nil, tx.db.expire(key, ttl, now)
+	})
+}
+
+// Persist 在事务中移除键的过期时间
+func (tx *Transaction) Persist(key string) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		return nil, tx.db.persist(key, now)
+	})
+}
+
+// IncrBy 在事务中把键的整数值加上 delta，结果为相加后的值
+func (tx *Transaction) IncrBy(key string, delta int64) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		return tx.db.incrBy(key, delta, now)
+	})
+}
+
+// IncrByFloat 在事务中把键的数值加上 delta，结果为相加后的值
+func (tx *Transaction) IncrByFloat(key string, delta float64) {
+	tx.queue(checkIncrement(delta), func(now time.Time) (interface{}, error) {
+		return tx.db.incrByFloat(key, delta, now)
+	})
+}
+
+// HSet 在事务中写入哈希字段，结果为新增字段的数量
+func (tx *Transaction) HSet(key string, fields map[string]string) {
+	var err error
+	if len(fields) == 0 {
+		err = fmt.Errorf("%w: at least one field is required", ErrInvalidValue)
+	}
+	tx.queue(err, func(now time.Time) (interface{}, error) {
+		added, changed, err := tx.db.hset(key, fields, now)
+		if err != nil || !changed {
+			return added, err
+		}
+		return added, tx.db.appendLog(aofRecord{Op: aofHSet, Key: key, Args: hsetArgs(fields)})
+	})
+}
+
+// HDel 在事务中删除哈希字段，结果为删除的数量
+func (tx *Transaction) HDel(key string, fields ...string) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		removed, err := tx.db.hdel(key, fields, now)
+		if err != nil || removed == 0 {
+			return removed, err
+		}
+		return removed, tx.db.appendLog(aofRecord{Op: aofHDel, Key: key, Args: fields})
+	})
+}
+
+// LPush 在事务中把元素插入列表头部，结果为插入后列表的长度
+func (tx *Transaction) LPush(key string, elements ...string) {
+	tx.push(key, elements, true)
+}
+
+// RPush 在事务中把元素追加到列表尾部，结果为追加后列表的长度
+func (tx *Transaction) RPush(key string, elements ...string) {
+	tx.push(key, elements, false)
+}
+
+// push 把 LPush 或 RPush 加入队列
+func (tx *Transaction) push(key string, elements []string, left bool) {
+	var err error
+	if len(elements) == 0 {
+		err = fmt.Errorf("%w: at least one element is required", ErrInvalidValue)
+	}
+	op := aofRPush
+	if left {
+		op = aofLPush
+	}
+	tx.queue(err, func(now time.Time) (interface{}, error) {
+		length, err := tx.db.push(key, elements, left, now)
+		if err != nil {
+			return nil, err
+		}
+		return length, tx.db.appendLog(aofRecord{Op: op, Key: key, Args: elements})
+	})
+}
+
+// SAdd 在事务中向集合添加成员，结果为新增成员的数量
+func (tx *Transaction) SAdd(key string, members ...string) {
+	var err error
+	if len(members) == 0 {
+		err = fmt.Errorf("%w: at least one member is required", ErrInvalidValue)
+	}
+	tx.queue(err, func(now time.Time) (interface{}, error) {
+		added, err := tx.db.sadd(key, members, now)
+		if err != nil || added == 0 {
+			return added, err
+		}
+		return added, tx.db.appendLog(aofRecord{Op: aofSAdd, Key: key, Args: members})
+	})
+}
+
+// SRem 在事务中从集合移除成员，结果为移除的数量
+func (tx *Transaction) SRem(key string, members ...string) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		removed, err := tx.db.srem(key, members, now)
+		if err != nil || removed == 0 {
+			return removed, err
+		}
+		return removed, tx.db.appendLog(aofRecord{Op: aofSRem, Key: key, Args: members})
+	})
+}
+
+// ZAdd 在事务中写入有序集合成员，结果为新增成员的数量
+func (tx *Transaction) ZAdd(key string, members ...ZMember) {
+	err := ZSetValue(members...).validate()
+	if len(members) == 0 {
+		err = fmt.Errorf("%w: at least one member is required", ErrInvalidValue)
+	}
+	tx.queue(err, func(now time.Time) (interface{}, error) {
+		added, changed, err := tx.db.zadd(key, members, now)
+		if err != nil || !changed {
+			return added, err
+		}
+		return added, tx.db.appendLog(aofRecord{Op: aofZAdd, Key: key, Members: members})
+	})
+}
+
+// ZRem 在事务中从有序集合移除成员，结果为移除的数量
+func (tx *Transaction) ZRem(key string, members ...string) {
+	tx.queue(nil, func(now time.Time) (interface{}, error) {
+		removed, err := tx.db.zrem(key, members, now)
+		if err != nil || removed == 0 {
+			return removed, err
+		}
+		return removed, tx.db.appendLog(aofRecord{Op: aofZRem, Key: key, Args: members})
+	})
+}
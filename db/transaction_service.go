@@ -0,0 +1,150 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// TxRequest 定义事务的请求体
+type TxRequest struct {
+	Watch    []WatchRequest     `json:"watch"`                       // 监视的键及期望的版本号
+	Commands []TxCommandRequest `json:"commands" binding:"required"` // 按顺序执行的操作
+}
+
+// WatchRequest 定义一个被监视的键，版本号通常来自查询时返回的 ETag
+type WatchRequest struct {
+	Key     string `json:"key" binding:"required"`
+	Version uint64 `json:"version"` // 期望的版本号，0 表示期望键不存在
+}
+
+// TxCommandRequest 定义事务中的一个操作
+type TxCommandRequest struct {
+	Op      string            `json:"op" binding:"required"`      // get、set、del、expire、persist、incrby、incrbyfloat、hset、hdel、lpush、rpush、sadd、srem、zadd、zrem
+	Key     string            `json:"key"`                        // 操作的键
+	Type    ValueType         `json:"type"`                       // set 写入的值的类型，省略时根据 value 推断
+	Value   json.RawMessage   `json:"value" swaggertype:"object"` // set 写入的值
+	TTL     *int64            `json:"ttl"`                        // set、expire 的过期秒数，set 省略时使用默认过期时间
+	Delta   json.Number       `json:"delta" swaggertype:"number"` // incrby、incrbyfloat 的增量
+	Fields  map[string]string `json:"fields"`                     // hset 写入的字段
+	Args    []string          `json:"args"`                       // hdel 的字段，lpush、rpush 的元素，sadd、srem、zrem 的成员
+	Members []ZMember         `json:"members"`                    // zadd 写入的成员和分数
+}
+
+// TxResultData 定义事务中一个操作的结果
+type TxResultData struct {
+	Value interface{} `json:"value,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// queue 把操作加入事务，defaultTTL 是 set 未指定过期时间时使用的默认值
+func (command TxCommandRequest) queue(tx *Transaction, defaultTTL time.Duration) error {
+	key := command.Key
+	switch command.Op {
+	case "get":
+		tx.Get(key)
+	case "set":
+		value, err := PutRequest{Type: command.Type, Value: command.Value}.value()
+		if err != nil {
+			return err
+		}
+		ttl := defaultTTL
+		if command.TTL != nil {
+			if ttl, err = ttlSeconds(*command.TTL); err != nil {
+				return err
+			}
+		}
+		tx.Set(key, value, ttl)
+	case "del":
+		tx.Delete(key)
+	case "expire":
+		if command.TTL == nil {
+			return fmt.Errorf("%w: expire requires ttl", ErrInvalidValue)
+		}
+		ttl, err := ttlSeconds(*command.TTL)
+		if err != nil {
+			return err
+		}
+		tx.Expire(key, ttl)
+	case "persist":
+		tx.Persist(key)
+	case "incrby":
+		delta, err := command.Delta.Int64()
+		if err != nil {
+			return fmt.Errorf("%w: incrby requires an integer delta", ErrInvalidValue)
+		}
+		tx.IncrBy(key, delta)
+	case "incrbyfloat":
+		delta, err := command.Delta.Float64()
+		if err != nil {
+			return fmt.Errorf("%w: incrbyfloat requires a numeric delta", ErrInvalidValue)
+		}
+		tx.IncrByFloat(key, delta)
+	case "hset":
+		tx.HSet(key, command.Fields)
+	case "hdel":
+		tx.HDel(key, command.Args...)
+	case "lpush":
+		tx.LPush(key, command.Args...)
+	case "rpush":
+		tx.RPush(key, command.Args...)
+	case "sadd":
+		tx.SAdd(key, command.Args...)
+	case "srem":
+		tx.SRem(key, command.Args...)
+	case "zadd":
+		tx.ZAdd(key, command.Members...)
+	case "zrem":
+		tx.ZRem(key, command.Args...)
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidValue, command.Op)
+	}
+	return nil
+}
+
+// ExecTransaction 原子地执行一批操作
+// @Summary 执行事务
+// @Description 按顺序原子地执行一批操作，对应 Redis 的 MULTI/EXEC。watch 中任意一个键的版本号与期望不符时整个事务被放弃并返回 412。
+// @Description 单个操作失败不会回滚其他操作，失败原因在对应结果的 error 中返回。
+// @Tags transaction
+// @Accept  json
+// @Produce  json
+// @Param body body TxRequest true "监视的键和操作"
+// @Success 200 {object} KVResponse "执行成功，data 为每个操作的结果"
+// @Failure 400 {object} KVResponse "无效的操作"
+// @Failure 412 {object} KVResponse "被监视的键已被修改"
+// @Router /tx [post]
+func (db *EchoDB) ExecTransaction(context *gin.Context) {
+	var request TxRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondInvalidInput(context)
+		return
+	}
+
+	tx := db.Multi()
+	for _, watch := range request.Watch {
+		tx.WatchVersion(watch.Key, watch.Version)
+	}
+	for i, command := range request.Commands {
+		if err := command.queue(tx, db.lifetime); err != nil {
+			respondError(context, fmt.Errorf("command %d: %w", i, err))
+			return
+		}
+	}
+
+	results, err := tx.Exec()
+	if err != nil {
+		respondError(context, err)
+		return
+	}
+
+	data := make([]TxResultData, len(results))
+	for i, result := range results {
+		data[i].Value = result.Value
+		if result.Err != nil {
+			data[i].Error = result.Err.Error()
+		}
+	}
+	respondSuccess(context, data)
+}
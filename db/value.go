@@ -0,0 +1,369 @@
+package db
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// ErrWrongType 表示对键执行的操作与其值的类型不符
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// ErrInvalidValue 表示无法把输入解析为受支持的值
+var ErrInvalidValue = errors.New("invalid value")
+
+// ValueType 是值的类型标签
+type ValueType string
+
+// 支持的值类型
+const (
+	TypeString ValueType = "string"
+	TypeInt    ValueType = "int"   // 64 位有符号整数
+	TypeFloat  ValueType = "float" // 64 位浮点数，不允许 NaN 和无穷大
+	TypeBytes  ValueType = "bytes" // 任意字节，JSON 中以 base64 表示
+	TypeList   ValueType = "list"  // 字符串列表
+	TypeHash   ValueType = "hash"  // 字段到字符串的映射
+	TypeSet    ValueType = "set"   // 字符串集合
+	TypeZSet   ValueType = "zset"  // 按分数排序的字符串集合
+)
+
+// ZMember 是有序集合中的一个成员
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// Value 是带类型标签的值，只有与标签对应的字段有意义
+// 集合类型的内容由 Value 独占，对外返回时一律复制，避免调用方绕过数据库的锁修改数据
+type Value struct {
+	kind  ValueType
+	str   string
+	num   int64
+	float float64
+	bytes []byte
+	list  []string
+	hash  map[string]string
+	set   map[string]struct{}
+	zset  *sortedSet
+}
+
+// StringValue 创建字符串值
+func StringValue(s string) Value {
+	return Value{kind: TypeString, str: s}
+}
+
+// IntValue 创建整数值
+func IntValue(n int64) Value {
+	return Value{kind: TypeInt, num: n}
+}
+
+// FloatValue 创建浮点数值，NaN 和无穷大在写入时会被拒绝
+func FloatValue(f float64) Value {
+	return Value{kind: TypeFloat, float: f}
+}
+
+// BytesValue 创建字节值
+func BytesValue(b []byte) Value {
+	return Value{kind: TypeBytes, bytes: bytes.Clone(b)}
+}
+
+// ListValue 创建列表值
+func ListValue(elements ...string) Value {
+	return Value{kind: TypeList, list: slices.Clone(elements)}
+}
+
+// HashValue 创建哈希值
+func HashValue(fields map[string]string) Value {
+	return Value{kind: TypeHash, hash: maps.Clone(fields)}
+}
+
+// SetValue 创建集合值，重复的成员只保留一个
+func SetValue(members ...string) Value {
+	set := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		set[member] = struct{}{}
+	}
+	return Value{kind: TypeSet, set: set}
+}
+
+// ZSetValue 创建有序集合值，重复的成员以最后出现的分数为准
+func ZSetValue(members ...ZMember) Value {
+	zset := newSortedSet()
+	for _, member := range members {
+		zset.add(member.Member, member.Score)
+	}
+	return Value{kind: TypeZSet, zset: zset}
+}
+
+// Type 返回值的类型
+func (v Value) Type() ValueType {
+	return v.kind
+}
+
+// AsString 返回字符串值
+func (v Value) AsString() (string, error) {
+	if v.kind != TypeString {
+		return "", ErrWrongType
+	}
+	return v.str, nil
+}
+
+// AsInt 返回整数值
+func (v Value) AsInt() (int64, error) {
+	if v.kind != TypeInt {
+		return 0, ErrWrongType
+	}
+	return v.num, nil
+}
+
+// AsFloat 返回浮点数值
+func (v Value) AsFloat() (float64, error) {
+	if v.kind != TypeFloat {
+		return 0, ErrWrongType
+	}
+	return v.float, nil
+}
+
+// AsBytes 返回字节值的副本
+func (v Value) AsBytes() ([]byte, error) {
+	if v.kind != TypeBytes {
+		return nil, ErrWrongType
+	}
+	return bytes.Clone(v.bytes), nil
+}
+
+// AsList 返回列表元素的副本
+func (v Value) AsList() ([]string, error) {
+	if v.kind != TypeList {
+		return nil, ErrWrongType
+	}
+	return slices.Clone(v.list), nil
+}
+
+// AsHash 返回哈希字段的副本
+func (v Value) AsHash() (map[string]string, error) {
+	if v.kind != TypeHash {
+		return nil, ErrWrongType
+	}
+	return maps.Clone(v.hash), nil
+}
+
+// AsSet 返回按字典序排列的集合成员
+func (v Value) AsSet() ([]string, error) {
+	if v.kind != TypeSet {
+		return nil, ErrWrongType
+	}
+	return slices.Sorted(maps.Keys(v.set)), nil
+}
+
+// AsZSet 返回按分数升序排列的有序集合成员，分数相同时按成员的字典序排列
+func (v Value) AsZSet() ([]ZMember, error) {
+	if v.kind != TypeZSet {
+		return nil, ErrWrongType
+	}
+
+	return v.zset.rangeByRank(0, v.zset.len()-1), nil
+}
+
+// compareZMembers 按 (分数, 成员) 比较有序集合的成员
+func compareZMembers(a, b ZMember) int {
+	if c := cmp.Compare(a.Score, b.Score); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Member, b.Member)
+}
+
+// Clone 返回值的深拷贝
+func (v Value) Clone() Value {
+	clone := v
+	clone.bytes = bytes.Clone(v.bytes)
+	clone.list = slices.Clone(v.list)
+	clone.hash = maps.Clone(v.hash)
+	clone.set = maps.Clone(v.set)
+	clone.zset = v.zset.clone()
+	return clone
+}
+
+// validate 检查值能否被存储并原样编码为 JSON
+func (v Value) validate() error {
+	switch v.kind {
+	case TypeString, TypeInt, TypeBytes, TypeList, TypeHash, TypeSet:
+		return nil
+	case TypeFloat:
+		if math.IsNaN(v.float) || math.IsInf(v.float, 0) {
+			return fmt.Errorf("%w: float must be finite", ErrInvalidValue)
+		}
+		return nil
+	case TypeZSet:
+		for _, score := range v.zset.scores {
+			if math.IsNaN(score) {
+				return fmt.Errorf("%w: score is not a number", ErrInvalidValue)
+			}
+			if math.IsInf(score, 0) {
+				return fmt.Errorf("%w: score must be finite", ErrInvalidValue)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidValue, v.kind)
+	}
+}
+
+// taggedValue 是值的 JSON 表示：{"type": 类型, "value": 内容}
+type taggedValue struct {
+	Type  ValueType       `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON 把值编码为带类型标签的 JSON
+// 集合和有序集合按固定顺序输出，相同的值总是得到相同的编码
+func (v Value) MarshalJSON() ([]byte, error) {
+	var content interface{}
+	switch v.kind {
+	case TypeString:
+		content = v.str
+	case TypeInt:
+		content = v.num
+	case TypeFloat:
+		content = v.float
+	case TypeBytes:
+		content = v.bytes
+	case TypeList:
+		content = v.list
+	case TypeHash:
+		content = v.hash
+	case TypeSet:
+		content, _ = v.AsSet()
+	case TypeZSet:
+		content, _ = v.AsZSet()
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidValue, v.kind)
+	}
+
+	// nil 的切片和 map 编码为空集合而不是 null
+	switch {
+	case v.kind == TypeList && v.list == nil:
+		content = []string{}
+	case v.kind == TypeHash && v.hash == nil:
+		content = map[string]string{}
+	}
+
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(taggedValue{Type: v.kind, Value: raw})
+}
+
+// UnmarshalJSON 解析带类型标签的 JSON
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var tagged taggedValue
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return err
+	}
+
+	value, err := DecodeValue(tagged.Type, tagged.Value)
+	if err != nil {
+		return err
+	}
+	*v = value
+	return nil
+}
+
+// DecodeValue 按指定类型解析 JSON 内容
+func DecodeValue(kind ValueType, raw json.RawMessage) (Value, error) {
+	var value Value
+	var err error
+
+	switch kind {
+	case TypeString:
+		var s string
+		err = json.Unmarshal(raw, &s)
+		value = StringValue(s)
+	case TypeInt:
+		var n int64
+		err = json.Unmarshal(raw, &n)
+		value = IntValue(n)
+	case TypeFloat:
+		var f float64
+		err = json.Unmarshal(raw, &f)
+		value = FloatValue(f)
+	case TypeBytes:
+		var b []byte
+		err = json.Unmarshal(raw, &b)
+		value = Value{kind: TypeBytes, bytes: b}
+	case TypeList:
+		var list []string
+		err = json.Unmarshal(raw, &list)
+		value = Value{kind: TypeList, list: list}
+	case TypeHash:
+		var hash map[string]string
+		err = json.Unmarshal(raw, &hash)
+		value = Value{kind: TypeHash, hash: hash}
+	case TypeSet:
+		var members []string
+		err = json.Unmarshal(raw, &members)
+		value = SetValue(members...)
+	case TypeZSet:
+		var members []ZMember
+		err = json.Unmarshal(raw, &members)
+		value = ZSetValue(members...)
+	default:
+		return Value{}, fmt.Errorf("%w: unknown type %q", ErrInvalidValue, kind)
+	}
+
+	if err != nil {
+		return Value{}, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	if err := value.validate(); err != nil {
+		return Value{}, err
+	}
+	return value, nil
+}
+
+// ParseJSONValue 根据 JSON 内容推断类型：字符串为 string，整数为 int，其他数字为 float，
+// 字符串数组为 list，值均为字符串的对象为 hash。其他类型需要显式指定
+func ParseJSONValue(raw json.RawMessage) (Value, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var content interface{}
+	if err := decoder.Decode(&content); err != nil {
+		return Value{}, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+
+	switch content := content.(type) {
+	case string:
+		return StringValue(content), nil
+	case json.Number:
+		if n, err := strconv.ParseInt(content.String(), 10, 64); err == nil {
+			return IntValue(n), nil
+		}
+		f, err := content.Float64()
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		return FloatValue(f), nil
+	case []interface{}:
+		return DecodeValue(TypeList, raw)
+	case map[string]interface{}:
+		return DecodeValue(TypeHash, raw)
+	default:
+		return Value{}, fmt.Errorf("%w: cannot infer the type of %s, specify it explicitly", ErrInvalidValue, raw)
+	}
+}
+
+// legacyValue 转换旧版本持久化文件中未带类型标签的值
+// 无法推断类型的值（布尔、null、嵌套结构等）按原始 JSON 文本保存为字符串，保证数据不丢失
+func legacyValue(raw json.RawMessage) Value {
+	if value, err := ParseJSONValue(raw); err == nil {
+		return value
+	}
+	return StringValue(string(raw))
+}
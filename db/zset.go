@@ -0,0 +1,260 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrMemberNotFound 表示有序集合中不存在请求的成员
+var ErrMemberNotFound = errors.New("member not found")
+
+// zsetIndexDegree 是有序集合排序索引每个节点最多容纳的成员数量
+const zsetIndexDegree = 32
+
+// sortedSet 是有序集合的存储结构
+// scores 按成员查分数，index 按 (分数, 成员) 排列所有成员。index 记录了子树大小，
+// 按排名定位成员和求成员的排名都只需要 O(log n)
+type sortedSet struct {
+	scores map[string]float64
+	index  *BPlusTree[ZMember, struct{}]
+}
+
+// newSortedSet 创建空的有序集合
+func newSortedSet() *sortedSet {
+	return &sortedSet{
+		scores: make(map[string]float64),
+		index:  NewBPlusTreeFunc[ZMember, struct{}](zsetIndexDegree, compareZMembers),
+	}
+}
+
+// len 返回成员数量
+func (z *sortedSet) len() int {
+	if z == nil {
+		return 0
+	}
+	return len(z.scores)
+}
+
+// add 写入成员的分数，返回成员是否是新增的
+func (z *sortedSet) add(member string, score float64) bool {
+	old, exists := z.scores[member]
+	if exists {
+		if old == score {
+			return false
+		}
+		z.index.Delete(ZMember{Member: member, Score: old})
+	}
+	z.scores[member] = score
+	z.index.Put(ZMember{Member: member, Score: score}, struct{}{})
+	return !exists
+}
+
+// remove 移除成员，返回成员是否存在
+func (z *sortedSet) remove(member string) bool {
+	score, exists := z.scores[member]
+	if !exists {
+		return false
+	}
+	delete(z.scores, member)
+	z.index.Delete(ZMember{Member: member, Score: score})
+	return true
+}
+
+// rank 返回成员按分数升序的排名，从 0 开始
+func (z *sortedSet) rank(member string) (int, bool) {
+	score, exists := z.scores[member]
+	if !exists {
+		return 0, false
+	}
+	rank, _ := z.index.Rank(ZMember{Member: member, Score: score})
+	return rank, true
+}
+
+// rangeByRank 返回排名在 [start, stop] 之间的成员，调用方保证下标在范围内
+func (z *sortedSet) rangeByRank(start, stop int) []ZMember {
+	count := max(stop-start+1, 0)
+	members := make([]ZMember, 0, count)
+	for it := z.index.SeekRank(start); it.Valid() && len(members) < count; it.Next() {
+		members = append(members, it.Key())
+	}
+	return members
+}
+
+// rangeByScore 返回分数在 [min, max] 之间的成员
+func (z *sortedSet) rangeByScore(min, max float64) []ZMember {
+	members := []ZMember{}
+	// 空字符串是最小的成员，定位到分数不小于 min 的第一个成员
+	for it := z.index.Seek(ZMember{Score: min}); it.Valid() && it.Key().Score <= max; it.Next() {
+		members = append(members, it.Key())
+	}
+	return members
+}
+
+// clone 返回有序集合的深拷贝
+func (z *sortedSet) clone() *sortedSet {
+	if z == nil {
+		return nil
+	}
+	clone := newSortedSet()
+	for member, score := range z.scores {
+		clone.add(member, score)
+	}
+	return clone
+}
+
+// ZAdd 写入有序集合的成员和分数，返回新增成员的数量，已有成员的分数会被更新
+// 同一个成员出现多次时以最后出现的分数为准。键不存在时创建一个永不过期的有序集合，已有键保留原来的过期时间
+func (db *EchoDB) ZAdd(key string, members ...ZMember) (int, error) {
+	if len(members) == 0 {
+		return 0, fmt.Errorf("%w: at least one member is required", ErrInvalidValue)
+	}
+	if err := ZSetValue(members...).validate(); err != nil {
+		return 0, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	added, changed, err := db.zadd(key, members, time.Now())
+	if err != nil || !changed {
+		return added, err
+	}
+	return added, db.appendLog(aofRecord{Op: aofZAdd, Key: key, Members: members})
+}
+
+// ZRem 从有序集合移除成员，返回实际移除的数量，最后一个成员被移除后键也会被删除
+func (db *EchoDB) ZRem(key string, members ...string) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.writable(); err != nil {
+		return 0, err
+	}
+	removed, err := db.zrem(key, members, time.Now())
+	if err != nil || removed == 0 {
+		return removed, err
+	}
+	return removed, db.appendLog(aofRecord{Op: aofZRem, Key: key, Args: members})
+}
+
+// ZRange 返回排名在 [start, stop] 之间的成员，按分数升序排列，下标规则与 LRange 相同
+// 键不存在时返回空切片
+func (db *EchoDB) ZRange(key string, start, stop int) ([]ZMember, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.readCollection(key, TypeZSet, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return []ZMember{}, nil
+	}
+
+	start, stop, ok := normalizeRange(start, stop, item.Value.zset.len())
+	if !ok {
+		return []ZMember{}, nil
+	}
+	return item.Value.zset.rangeByRank(start, stop), nil
+}
+
+// ZRangeByScore 返回分数在 [min, max] 之间的成员，按分数升序排列
+// min 和 max 可以是无穷大，键不存在时返回空切片
+func (db *EchoDB) ZRangeByScore(key string, min, max float64) ([]ZMember, error) {
+	if math.IsNaN(min) || math.IsNaN(max) {
+		return nil, fmt.Errorf("%w: score bound is not a number", ErrInvalidValue)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.readCollection(key, TypeZSet, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || min > max {
+		return []ZMember{}, nil
+	}
+	return item.Value.zset.rangeByScore(min, max), nil
+}
+
+// ZRank 返回成员按分数升序的排名，从 0 开始
+func (db *EchoDB) ZRank(key, member string) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.readCollection(key, TypeZSet, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if item == nil {
+		return 0, ErrKeyNotFound
+	}
+
+	rank, exists := item.Value.zset.rank(member)
+	if !exists {
+		return 0, ErrMemberNotFound
+	}
+	return rank, nil
+}
+
+// zadd 是 ZAdd 的实现，返回新增成员的数量以及有序集合是否被修改，调用方必须持有写锁
+func (db *EchoDB) zadd(key string, members []ZMember, now time.Time) (int, bool, error) {
+	// 去重，同一个成员以最后出现的分数为准
+	scores := make(map[string]float64, len(members))
+	for _, member := range members {
+		scores[member.Member] = member.Score
+	}
+	growth := func(value Value) int64 {
+		var delta int64
+		for member := range scores {
+			if _, exists := value.zset.scores[member]; !exists {
+				delta += zsetMemberSize(member)
+			}
+		}
+		return delta
+	}
+
+	item, err := db.growCollection(key, TypeZSet, now, growth)
+	if err != nil {
+		return 0, false, err
+	}
+
+	delta, added, changed := growth(item.Value), 0, false
+	for member, score := range scores {
+		if old, exists := item.Value.zset.scores[member]; !exists || old != score {
+			changed = true
+		}
+		if item.Value.zset.add(member, score) {
+			added++
+		}
+	}
+	db.resize(key, item, delta, changed)
+	return added, changed, nil
+}
+
+// zrem 是 ZRem 的实现，调用方必须持有写锁
+func (db *EchoDB) zrem(key string, members []string, now time.Time) (int, error) {
+	item, err := db.readCollection(key, TypeZSet, now)
+	if err != nil || item == nil {
+		return 0, err
+	}
+
+	var delta int64
+	removed := 0
+	for _, member := range members {
+		if item.Value.zset.remove(member) {
+			delta -= zsetMemberSize(member)
+			removed++
+		}
+	}
+	if removed > 0 {
+		db.resize(key, item, delta, true)
+	}
+	return removed, nil
+}
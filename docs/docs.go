@@ -15,47 +15,1797 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/aof": {
+            "get": {
+                "description": "返回AOF当前的文件长度、上次重写后的基准长度、是否正在重写以及最近一次重写的结果。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "查询AOF状态",
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/aof/rewrite": {
+            "post": {
+                "description": "在后台根据当前数据生成最精简的追加写日志并原子地替换原文件，重写期间的新写入不会丢失。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "重写AOF",
+                "responses": {
+                    "202": {
+                        "description": "已开始重写",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "未开启AOF或已有重写正在进行",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cluster/join": {
+            "post": {
+                "description": "向种子节点发送加入请求并获取成员列表，种子节点会把本节点的加入宣告给集群，之后本节点与新发现的成员同步数据。\n无需修改其他节点的 gossip.peers。离开集群的节点也可以通过该接口重新加入。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "加入集群",
+                "parameters": [
+                    {
+                        "description": "种子节点地址",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.JoinClusterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "加入成功，data 为成员列表",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "未开启 Gossip",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "所有种子节点都无法访问",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cluster/leave": {
+            "post": {
+                "description": "先把积压的变更发送给其他成员，并与一个成员完成反熵同步交出本节点的数据，然后发送交接期间的写入并向集群宣告离开。\n离开后本节点拒绝写入并返回 503，不再探测、复制和同步，可以安全地关闭。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "离开集群",
+                "responses": {
+                    "200": {
+                        "description": "已离开集群，data 为成员列表",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "未开启 Gossip",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "没有成员能够接收本节点的数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/snapshot": {
+            "get": {
+                "description": "返回是否正在保存快照，以及最近一次保存的时间、文件、键数量和失败原因。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "查询快照状态",
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "在后台把当前数据保存为一个新的快照文件，保存进度和结果通过 GET /admin/snapshot 查询。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "保存快照",
+                "responses": {
+                    "202": {
+                        "description": "已开始保存",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "已有快照正在保存",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/batch/delete": {
+            "post": {
+                "description": "一次删除多个键，结果与请求中的键一一对应，不存在的键在 error 中说明。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batch"
+                ],
+                "summary": "批量删除",
+                "parameters": [
+                    {
+                        "description": "删除的键",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.KeysRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除完成，data 为 BatchData",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/batch/get": {
+            "post": {
+                "description": "一次查询多个键，结果与请求中的键一一对应，不存在的键在 error 中说明。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batch"
+                ],
+                "summary": "批量查询",
+                "parameters": [
+                    {
+                        "description": "查询的键",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.KeysRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询完成，data 为 BatchData",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/batch/set": {
+            "post": {
+                "description": "一次写入多个键，各个键独立写入，部分键失败时其他键仍会写入，失败原因在对应结果的 error 中说明。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batch"
+                ],
+                "summary": "批量写入",
+                "parameters": [
+                    {
+                        "description": "写入的键值",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.BatchSetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "写入完成，data 为 BatchData",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cluster/members": {
+            "get": {
+                "description": "返回 SWIM 协议维护的成员列表，每个成员带有状态（alive、suspect、dead、left）、incarnation 以及进入当前状态的时间。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cluster"
+                ],
+                "summary": "查询集群成员",
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "未开启 Gossip",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/db/echoDB/check-update": {
             "get": {
-                "description": "根据客户端提供的当前版本号，检查是否需要更新。",
-                "consumes": [
+                "description": "根据客户端提供的当前版本号，检查是否需要更新。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "update"
+                ],
+                "summary": "检查更新",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "v1.0.0",
+                        "description": "当前版本号",
+                        "name": "current_version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回最新版本信息",
+                        "schema": {
+                            "$ref": "#/definitions/db.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/db.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/db/echoDB/update-version": {
+            "post": {
+                "description": "更新服务器上记录的最新版本号。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "update"
+                ],
+                "summary": "更新应用版本",
+                "parameters": [
+                    {
+                        "description": "新的版本号",
+                        "name": "new_version",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "版本更新成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv": {
+            "get": {
+                "description": "按键的顺序分页返回数据项。指定 prefix 时返回带有该前缀的键，否则返回 [start, end] 区间内的键，end 为空时不设上界；两种方式都可以通过 reverse 按降序返回。\n返回的 next_cursor 不为空时，携带该游标再次请求即可获取下一页。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "范围查询",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "起始键（包含）",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束键（包含）",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "键前缀",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否按降序返回",
+                        "name": "reverse",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "每页最多返回的条目数",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "上一页返回的 next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}": {
+            "get": {
+                "description": "根据键名返回对应的值以及过期时间、访问统计、版本号等元数据，版本号同时通过 ETag 响应头返回。\n开启 Gossip 时返回的 stamp 和 clock 是键的 HLC 时间戳和版本向量；按 siblings 解决冲突时，siblings 中是与当前值并发写入的其他值，重新写入该键即解决冲突。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "查询键值",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "键的版本号"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "键不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "写入新键或覆盖已有键的值。type 可选 string、int、float、bytes、list、hash、set、zset，省略时根据 value 推断；可通过 ttl 指定过期秒数，0 表示永不过期。\n条件写入：If-None-Match: * 仅在键不存在时写入，If-Match: * 仅在键存在时写入，If-Match: \"版本号\" 仅在版本号一致时写入，条件不满足时返回 412。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "写入键值",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "写入的值",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.PutRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "* 或期望的版本号",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "只支持 *",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "写入成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "写入后的版本号"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "写入条件不满足",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除指定的键。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "删除键值",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "键不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/decr": {
+            "post": {
+                "description": "把键的整数值减去 delta 并返回结果，delta 省略时为 1。键不存在时从 0 开始计算并创建永不过期的键，已有键保留原来的过期时间。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "counter"
+                ],
+                "summary": "整数自减",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "减量",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/db.IncrRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "自减成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "值不是整数或结果溢出",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/expire": {
+            "post": {
+                "description": "为已有键设置新的过期秒数，不为正数时立即删除该键。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "设置过期时间",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "过期秒数",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.ExpireRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "设置成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "键不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/hash": {
+            "get": {
+                "description": "返回哈希的所有字段和值，键不存在时返回空的哈希。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hash"
+                ],
+                "summary": "查询哈希的所有字段",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是哈希",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "写入哈希的一个或多个字段，返回新增字段的数量。键不存在时创建永不过期的哈希。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hash"
+                ],
+                "summary": "写入哈希字段",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "字段和值",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.HSetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "写入成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是哈希",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/hash/{field}": {
+            "get": {
+                "description": "返回哈希中一个字段的值。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hash"
+                ],
+                "summary": "查询哈希字段",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "字段名",
+                        "name": "field",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "键或字段不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是哈希",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除哈希中的一个字段，返回实际删除的数量。最后一个字段被删除后键也会被删除。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hash"
+                ],
+                "summary": "删除哈希字段",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "字段名",
+                        "name": "field",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "删除成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是哈希",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/incr": {
+            "post": {
+                "description": "把键的整数值加上 delta 并返回结果，delta 省略时为 1。键不存在时从 0 开始计算并创建永不过期的键，已有键保留原来的过期时间。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "counter"
+                ],
+                "summary": "整数自增",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "增量",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/db.IncrRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "自增成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "值不是整数或结果溢出",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/incrbyfloat": {
+            "post": {
+                "description": "把键的数值加上 delta 并以浮点数保存，返回相加后的结果。键不存在时从 0 开始计算并创建永不过期的键，已有键保留原来的过期时间。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "counter"
+                ],
+                "summary": "浮点数自增",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "增量",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.IncrByFloatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "自增成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "值不是数字或结果不是有限数",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/list": {
+            "get": {
+                "description": "返回列表中下标在 [start, stop] 之间的元素，负数下标从列表末尾倒数。默认返回整个列表，键不存在时返回空列表。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "list"
+                ],
+                "summary": "查询列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "起始下标（包含），默认 0",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "结束下标（包含），默认 -1",
+                        "name": "stop",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的下标",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是列表",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/list/lpop": {
+            "post": {
+                "description": "从列表头部弹出最多 count 个元素，键不存在时返回空列表。最后一个元素被弹出后键也会被删除。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "list"
+                ],
+                "summary": "列表头部弹出",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "弹出的数量，默认 1",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "弹出成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的数量",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是列表",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/list/lpush": {
+            "post": {
+                "description": "把元素依次插入列表头部，返回插入后列表的长度。键不存在时创建永不过期的列表。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "list"
+                ],
+                "summary": "列表头部插入",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "插入的元素",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.PushRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "插入成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是列表",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/list/rpop": {
+            "post": {
+                "description": "从列表尾部弹出最多 count 个元素，键不存在时返回空列表。最后一个元素被弹出后键也会被删除。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "list"
+                ],
+                "summary": "列表尾部弹出",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "弹出的数量，默认 1",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "弹出成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的数量",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是列表",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/list/rpush": {
+            "post": {
+                "description": "把元素依次追加到列表尾部，返回追加后列表的长度。键不存在时创建永不过期的列表。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "list"
+                ],
+                "summary": "列表尾部追加",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "追加的元素",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.PushRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "追加成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是列表",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/persist": {
+            "post": {
+                "description": "移除已有键的过期时间，使其永不过期。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "移除过期时间",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "设置成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "键不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/set": {
+            "get": {
+                "description": "返回按字典序排列的集合成员，键不存在时返回空集合。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "set"
+                ],
+                "summary": "查询集合成员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "向集合添加一个或多个成员，返回新增成员的数量。键不存在时创建永不过期的集合。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "set"
+                ],
+                "summary": "添加集合成员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "添加的成员",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.MembersRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "添加成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/set/{member}": {
+            "delete": {
+                "description": "从集合移除一个成员，返回实际移除的数量。最后一个成员被移除后键也会被删除。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "set"
+                ],
+                "summary": "移除集合成员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "成员",
+                        "name": "member",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "移除成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/ttl": {
+            "get": {
+                "description": "返回键的剩余存活秒数，永不过期的键返回 -1。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "查询剩余存活时间",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "键不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/type": {
+            "get": {
+                "description": "返回键对应的值的类型：string、int、float、bytes、list、hash、set 或 zset。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kv"
+                ],
+                "summary": "查询值类型",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "键不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/zset": {
+            "get": {
+                "description": "返回排名在 [start, stop] 之间的成员，按分数升序排列，负数下标从末尾倒数。默认返回所有成员，键不存在时返回空列表。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "zset"
+                ],
+                "summary": "按排名查询有序集合",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "起始排名（包含），默认 0",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "结束排名（包含），默认 -1",
+                        "name": "stop",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的下标",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是有序集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "写入一个或多个成员及其分数，返回新增成员的数量，已有成员的分数会被更新。键不存在时创建永不过期的有序集合。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "zset"
+                ],
+                "summary": "写入有序集合成员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "成员和分数",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.ZAddRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "写入成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的输入数据",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是有序集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/zset/rank/{member}": {
+            "get": {
+                "description": "返回成员按分数升序的排名，从 0 开始。",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "zset"
+                ],
+                "summary": "查询成员排名",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "成员",
+                        "name": "member",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "键或成员不存在",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是有序集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/kv/{key}/zset/score": {
+            "get": {
+                "description": "返回分数在 [min, max] 之间的成员，按分数升序排列。min 和 max 可以是 -inf、inf 或 +inf，默认不限制，键不存在时返回空列表。查询字符串中的 + 会被解码为空格，+inf 需要写成 %2Binf，也可以直接写 inf。",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "update"
+                    "zset"
                 ],
-                "summary": "检查更新",
+                "summary": "按分数查询有序集合",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "v1.0.0",
-                        "description": "当前版本号",
-                        "name": "current_version",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "最小分数（包含），默认 -inf",
+                        "name": "min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "最大分数（包含），默认 +inf，正无穷可写作 inf 或 %2Binf",
+                        "name": "max",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功返回最新版本信息",
+                        "description": "查询成功",
                         "schema": {
-                            "$ref": "#/definitions/db.Response"
+                            "$ref": "#/definitions/db.KVResponse"
                         }
                     },
                     "400": {
-                        "description": "请求参数错误",
+                        "description": "无效的分数",
                         "schema": {
-                            "$ref": "#/definitions/db.Response"
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是有序集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
                         }
                     }
                 }
             }
         },
-        "/db/echoDB/update-version": {
+        "/kv/{key}/zset/{member}": {
+            "delete": {
+                "description": "从有序集合移除一个成员，返回实际移除的数量。最后一个成员被移除后键也会被删除。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "zset"
+                ],
+                "summary": "移除有序集合成员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "键名",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "成员",
+                        "name": "member",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "移除成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "键的类型不是有序集合",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sets/inter": {
+            "get": {
+                "description": "返回所有指定集合的交集，按字典序排列。任意一个键不存在时交集为空。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "set"
+                ],
+                "summary": "集合交集",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "参与求交的键，可以重复指定",
+                        "name": "key",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "未指定键",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "存在类型不是集合的键",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "description": "返回键数量、估算的内存占用、容量上限以及淘汰和过期的累计数量。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "查询统计信息",
+                "responses": {
+                    "200": {
+                        "description": "查询成功",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tx": {
             "post": {
-                "description": "更新服务器上记录的最新版本号。",
+                "description": "按顺序原子地执行一批操作，对应 Redis 的 MULTI/EXEC。watch 中任意一个键的版本号与期望不符时整个事务被放弃并返回 412。\n单个操作失败不会回滚其他操作，失败原因在对应结果的 error 中返回。",
                 "consumes": [
                     "application/json"
                 ],
@@ -63,31 +1813,37 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "update"
+                    "transaction"
                 ],
-                "summary": "更新应用版本",
+                "summary": "执行事务",
                 "parameters": [
                     {
-                        "description": "新的版本号",
-                        "name": "new_version",
+                        "description": "监视的键和操作",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/db.TxRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "版本更新成功",
+                        "description": "执行成功，data 为每个操作的结果",
                         "schema": {
-                            "$ref": "#/definitions/db.Response"
+                            "$ref": "#/definitions/db.KVResponse"
                         }
                     },
                     "400": {
-                        "description": "无效的输入数据",
+                        "description": "无效的操作",
                         "schema": {
-                            "$ref": "#/definitions/db.Response"
+                            "$ref": "#/definitions/db.KVResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "被监视的键已被修改",
+                        "schema": {
+                            "$ref": "#/definitions/db.KVResponse"
                         }
                     }
                 }
@@ -95,6 +1851,187 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "db.BatchSetEntry": {
+            "type": "object",
+            "required": [
+                "value"
+            ],
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "过期秒数，省略时使用默认过期时间，0 表示永不过期",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "值的类型，省略时根据 value 推断",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.ValueType"
+                        }
+                    ]
+                },
+                "value": {
+                    "description": "值的内容，格式由 type 决定",
+                    "type": "object"
+                }
+            }
+        },
+        "db.BatchSetRequest": {
+            "type": "object",
+            "required": [
+                "entries"
+            ],
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.BatchSetEntry"
+                    }
+                }
+            }
+        },
+        "db.ExpireRequest": {
+            "type": "object",
+            "required": [
+                "ttl"
+            ],
+            "properties": {
+                "ttl": {
+                    "description": "过期秒数，不为正数时立即删除该键",
+                    "type": "integer"
+                }
+            }
+        },
+        "db.HSetRequest": {
+            "type": "object",
+            "required": [
+                "fields"
+            ],
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "db.IncrByFloatRequest": {
+            "type": "object",
+            "required": [
+                "delta"
+            ],
+            "properties": {
+                "delta": {
+                    "description": "增量，可以为负数",
+                    "type": "number"
+                }
+            }
+        },
+        "db.IncrRequest": {
+            "type": "object",
+            "properties": {
+                "delta": {
+                    "description": "增量，省略时为 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "db.JoinClusterRequest": {
+            "type": "object",
+            "required": [
+                "seeds"
+            ],
+            "properties": {
+                "seeds": {
+                    "description": "种子节点的 Gossip 地址，任意一个可以访问即可加入",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "db.KVResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "data": {},
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.KeysRequest": {
+            "type": "object",
+            "required": [
+                "keys"
+            ],
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "db.MembersRequest": {
+            "type": "object",
+            "required": [
+                "members"
+            ],
+            "properties": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "db.PushRequest": {
+            "type": "object",
+            "required": [
+                "elements"
+            ],
+            "properties": {
+                "elements": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "db.PutRequest": {
+            "type": "object",
+            "required": [
+                "value"
+            ],
+            "properties": {
+                "ttl": {
+                    "description": "过期秒数，省略时使用默认过期时间，0 表示永不过期",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "值的类型，省略时根据 value 推断",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.ValueType"
+                        }
+                    ]
+                },
+                "value": {
+                    "description": "值的内容，格式由 type 决定",
+                    "type": "object"
+                }
+            }
+        },
         "db.Response": {
             "type": "object",
             "properties": {
@@ -109,6 +2046,85 @@ const docTemplate = `{
                 }
             }
         },
+        "db.TxCommandRequest": {
+            "type": "object",
+            "required": [
+                "op"
+            ],
+            "properties": {
+                "args": {
+                    "description": "hdel 的字段，lpush、rpush 的元素，sadd、srem、zrem 的成员",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "delta": {
+                    "description": "incrby、incrbyfloat 的增量",
+                    "type": "number"
+                },
+                "fields": {
+                    "description": "hset 写入的字段",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "key": {
+                    "description": "操作的键",
+                    "type": "string"
+                },
+                "members": {
+                    "description": "zadd 写入的成员和分数",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ZMember"
+                    }
+                },
+                "op": {
+                    "description": "get、set、del、expire、persist、incrby、incrbyfloat、hset、hdel、lpush、rpush、sadd、srem、zadd、zrem",
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "set、expire 的过期秒数，set 省略时使用默认过期时间",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "set 写入的值的类型，省略时根据 value 推断",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.ValueType"
+                        }
+                    ]
+                },
+                "value": {
+                    "description": "set 写入的值",
+                    "type": "object"
+                }
+            }
+        },
+        "db.TxRequest": {
+            "type": "object",
+            "required": [
+                "commands"
+            ],
+            "properties": {
+                "commands": {
+                    "description": "按顺序执行的操作",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.TxCommandRequest"
+                    }
+                },
+                "watch": {
+                    "description": "监视的键及期望的版本号",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.WatchRequest"
+                    }
+                }
+            }
+        },
         "db.UpdateData": {
             "type": "object",
             "properties": {
@@ -122,6 +2138,78 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "db.ValueType": {
+            "type": "string",
+            "enum": [
+                "string",
+                "int",
+                "float",
+                "bytes",
+                "list",
+                "hash",
+                "set",
+                "zset"
+            ],
+            "x-enum-comments": {
+                "TypeBytes": "任意字节，JSON 中以 base64 表示",
+                "TypeFloat": "64 位浮点数，不允许 NaN 和无穷大",
+                "TypeHash": "字段到字符串的映射",
+                "TypeInt": "64 位有符号整数",
+                "TypeList": "字符串列表",
+                "TypeSet": "字符串集合",
+                "TypeZSet": "按分数排序的字符串集合"
+            },
+            "x-enum-varnames": [
+                "TypeString",
+                "TypeInt",
+                "TypeFloat",
+                "TypeBytes",
+                "TypeList",
+                "TypeHash",
+                "TypeSet",
+                "TypeZSet"
+            ]
+        },
+        "db.WatchRequest": {
+            "type": "object",
+            "required": [
+                "key"
+            ],
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "期望的版本号，0 表示期望键不存在",
+                    "type": "integer"
+                }
+            }
+        },
+        "db.ZAddRequest": {
+            "type": "object",
+            "required": [
+                "members"
+            ],
+            "properties": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ZMember"
+                    }
+                }
+            }
+        },
+        "db.ZMember": {
+            "type": "object",
+            "properties": {
+                "member": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                }
+            }
         }
     }
 }`
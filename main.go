@@ -21,23 +21,47 @@ func main() {
 	}
 
 	// 创建EchoDB实例
-	echoDB := db.NewEchoDB(config)
+	echoDB, err := db.NewEchoDB(config)
+	if err != nil {
+		log.Fatalf("Error creating EchoDB: %v", err)
+	}
 
-	engine := db.NewGossipEngine(config.Gossip.Peers, config.Gossip.Port, config.Gossip.NodeID)
+	// 根据配置选择一致性算法并启动服务，定期把写入传播给其他节点
+	if echoDB.Gossip != nil {
+		echoDB.Gossip.StartGossipServer()
 
-	go engine.StartGossipServer()
+		// 通过配置的种子节点加入集群，其他节点不需要把本节点写入配置
+		go func() {
+			if _, err := echoDB.Gossip.Join(config.Gossip.Peers); err != nil {
+				fmt.Printf("Failed to join the cluster: %v\n", err)
+			}
+		}()
 
-	// 启动Gossip传播，每5秒一次
-	ticker := time.NewTicker(5 * time.Second)
-	go func() {
-		for range ticker.C {
-			engine.Gossip()
-		}
-	}()
+		ticker := time.NewTicker(time.Duration(max(config.Gossip.Interval, 1)) * time.Millisecond)
+		go func() {
+			for range ticker.C {
+				echoDB.Gossip.Gossip()
+			}
+		}()
 
-	// 根据配置选择一致性算法并启动服务
-	if config.ConsistencyAlgorithm == "Gossip" {
-		go echoDB.Gossip.StartGossipServer()
+		// 按 SWIM 协议探测成员，发现加入、故障和恢复的节点
+		go echoDB.Gossip.StartFailureDetector(
+			time.Duration(max(config.Gossip.ProbeInterval, 1))*time.Millisecond,
+			time.Duration(config.Gossip.ProbeTimeout)*time.Millisecond,
+			time.Duration(config.Gossip.SuspicionTimeout)*time.Millisecond,
+			time.Duration(config.Gossip.ReapTimeout)*time.Second,
+			config.Gossip.IndirectChecks,
+		)
+
+		// 定期与随机节点进行反熵同步，修复推送时丢失的写入
+		if config.Gossip.SyncInterval > 0 {
+			syncTicker := time.NewTicker(time.Duration(config.Gossip.SyncInterval) * time.Second)
+			go func() {
+				for range syncTicker.C {
+					echoDB.Gossip.AntiEntropy()
+				}
+			}()
+		}
 	}
 
 	// 从MySQL加载数据
@@ -55,6 +79,56 @@ func main() {
 	router.GET("/check-update", db.NeedsUpdate)
 
 	router.POST("/update-version", db.UpdateVersion)
+
+	// 键值操作接口
+	router.GET("/kv", echoDB.RangeKeys)
+	router.GET("/kv/:key", echoDB.GetKey)
+	router.PUT("/kv/:key", echoDB.PutKey)
+	router.DELETE("/kv/:key", echoDB.DeleteKey)
+	router.GET("/kv/:key/ttl", echoDB.GetTTL)
+	router.GET("/kv/:key/type", echoDB.GetType)
+	router.POST("/kv/:key/incr", echoDB.IncrKey)
+	router.POST("/kv/:key/decr", echoDB.DecrKey)
+	router.POST("/kv/:key/incrbyfloat", echoDB.IncrByFloatKey)
+	router.POST("/kv/:key/expire", echoDB.ExpireKey)
+	router.POST("/kv/:key/persist", echoDB.PersistKey)
+	router.GET("/stats", echoDB.GetStats)
+	router.POST("/tx", echoDB.ExecTransaction)
+	router.POST("/batch/get", echoDB.BatchGet)
+	router.POST("/batch/set", echoDB.BatchSet)
+	router.POST("/batch/delete", echoDB.BatchDelete)
+
+	// 集合类型接口
+	router.POST("/kv/:key/hash", echoDB.HSetFields)
+	router.GET("/kv/:key/hash", echoDB.HGetAllFields)
+	router.GET("/kv/:key/hash/:field", echoDB.HGetField)
+	router.DELETE("/kv/:key/hash/:field", echoDB.HDelField)
+	router.POST("/kv/:key/list/lpush", echoDB.LPushElements)
+	router.POST("/kv/:key/list/rpush", echoDB.RPushElements)
+	router.POST("/kv/:key/list/lpop", echoDB.LPopElements)
+	router.POST("/kv/:key/list/rpop", echoDB.RPopElements)
+	router.GET("/kv/:key/list", echoDB.LRangeElements)
+	router.POST("/kv/:key/set", echoDB.SAddMembers)
+	router.GET("/kv/:key/set", echoDB.SMembersList)
+	router.DELETE("/kv/:key/set/:member", echoDB.SRemMember)
+	router.GET("/sets/inter", echoDB.SInterMembers)
+	router.POST("/kv/:key/zset", echoDB.ZAddMembers)
+	router.GET("/kv/:key/zset", echoDB.ZRangeMembers)
+	router.GET("/kv/:key/zset/score", echoDB.ZRangeByScoreMembers)
+	router.GET("/kv/:key/zset/rank/:member", echoDB.ZRankMember)
+	router.DELETE("/kv/:key/zset/:member", echoDB.ZRemMember)
+
+	// 管理接口
+	router.POST("/admin/snapshot", echoDB.SaveSnapshot)
+	router.GET("/admin/snapshot", echoDB.GetSnapshotStatus)
+	router.POST("/admin/aof/rewrite", echoDB.RewriteAOFLog)
+	router.GET("/admin/aof", echoDB.GetAOFStatus)
+
+	// 集群接口
+	router.GET("/cluster/members", echoDB.GetMembers)
+	router.POST("/admin/cluster/join", echoDB.JoinCluster)
+	router.POST("/admin/cluster/leave", echoDB.LeaveCluster)
+
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// 启动服务